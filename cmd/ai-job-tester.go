@@ -1,64 +1,296 @@
 package main
 
 import (
-	"crypto/tls"
+	"context"
+	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"livepeer-job-tester/internal/config"
+	"livepeer-job-tester/internal/coordination"
+	"livepeer-job-tester/internal/httpclient"
+	"livepeer-job-tester/internal/logging"
 	"livepeer-job-tester/internal/server"
 	"livepeer-job-tester/internal/services"
-	"log"
+	"livepeer-job-tester/internal/tracing"
+	"log/slog"
 	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
 	"time"
 )
 
 // main is the entry point of the application. It loads the configuration file, sets up the HTTP client,
-// initializes the Livepeer service, and starts the embedded webhook server. It also invokes the test job logic.
+// initializes the Livepeer service, and starts the embedded webhook server. It also invokes the test job logic,
+// either once or, with -serve, in a long-running mode where rounds run on an interval or through the admin API,
+// and the configuration is reloaded when the file changes or on SIGHUP. It exits with status 1 when run fails.
 func main() {
-	// Parse command-line flags to get the configuration file path.
+	if err := run(); err != nil {
+		slog.Error("Job tester failed", "err", err)
+		os.Exit(1)
+	}
+}
+
+// run runs the job tester, see main. Batched stats and pending spans are flushed before it returns.
+func run() error {
+	// Parse command-line flags to get the configuration file path and run mode.
 	configFile := flag.String("f", "configs/config.json", "path to the config file")
+	serve := flag.Bool("serve", false, "keep running and serve the admin API instead of exiting after one round")
+	interval := flag.Duration("interval", 0, "with -serve, run a full round at this interval (e.g. 1h); 0 runs rounds only on demand")
+	logLevel := flag.String("log-level", "", "log level (debug, info, warn or error), overrides logLevel in the config file")
+	logFormat := flag.String("log-format", "", "log format (json or logfmt), overrides logFormat in the config file")
+	printConfig := flag.Bool("print-config", false, "print the resolved configuration, with secrets redacted, and exit")
+	profile := flag.String("profile", os.Getenv("JOBTESTER_PROFILE"), "comma-separated profiles of the config file to apply, in order (e.g. smoke)")
+	reloadInterval := flag.Duration("reload-interval", 10*time.Second, "with -serve, how often to check the config file for changes; 0 reloads only on SIGHUP")
 	flag.Parse()
 
-	// Load the configuration file.
-	configLoader := &config.JSONConfigLoader{}
+	// Load the configuration file, in JSON, YAML or TOML format depending on its extension, with its includes and profiles.
+	var profiles []string
+	for _, name := range strings.Split(*profile, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			profiles = append(profiles, name)
+		}
+	}
+	configLoader := config.NewLoader(*configFile, profiles...)
 	cfg, err := configLoader.Load(*configFile)
 	if err != nil {
-		log.Fatalf("Error loading config: %v", err)
+		return fmt.Errorf("error loading config: %w", err)
+	}
+	if *printConfig {
+		out, err := json.MarshalIndent(cfg.Redacted(), "", "  ")
+		if err != nil {
+			return fmt.Errorf("error printing config: %w", err)
+		}
+		fmt.Println(string(out))
+		return nil
 	}
 
-	// Create an HTTP client with a custom transport.
-	client := createHTTPClient()
+	// Set up structured logging. Flags take precedence over the configuration file.
+	if *logLevel != "" {
+		cfg.LogLevel = *logLevel
+	}
+	if *logFormat != "" {
+		cfg.LogFormat = *logFormat
+	}
+	logger, err := logging.New(os.Stderr, cfg.LogFormat, cfg.LogLevel)
+	if err != nil {
+		return fmt.Errorf("error setting up logging: %w", err)
+	}
+	slog.SetDefault(logger)
+	slog.Debug("Loaded configuration", "file", *configFile, "profiles", profiles, "config", cfg.Redacted())
 
-	// Initialize the Livepeer service with the HTTP client and loaded configuration.
-	livepeerService := services.NewHTTPLivepeerService(client, cfg)
+	// Set up tracing. Batched stats and pending spans are flushed before exiting.
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.Tracing)
+	if err != nil {
+		return fmt.Errorf("error setting up tracing: %w", err)
+	}
+	var webhookServers []*server.EmbeddedWebhookServer
+	var shutdownOnce sync.Once
+	shutdown := func() {
+		shutdownOnce.Do(func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			for _, webhookServer := range webhookServers {
+				webhookServer.FlushStats(ctx)
+			}
+			if err := shutdownTracing(ctx); err != nil {
+				slog.Error("Error flushing traces", "err", err)
+			}
+		})
+	}
+	defer shutdown()
+
+	// Connect to the store shared with the testers of other regions, if coordinated.
+	var coordinationStore coordination.Store
+	if cfg.Coordination != nil {
+		client, err := createHTTPClient(cfg)
+		if err != nil {
+			return fmt.Errorf("error creating HTTP client for coordination: %w", err)
+		}
+		coordinationStore, err = coordination.New(cfg.Coordination, client)
+		if err != nil {
+			return fmt.Errorf("error setting up coordination: %w", err)
+		}
+	}
 
-	// Create and start the embedded webhook server.
-	webhookServer := server.NewEmbeddedWebhookServer(cfg, client, livepeerService)
+	// Create an embedded webhook server per gateway; a single gateway is configured by the top-level fields.
+	// Servers sharing a listener are served together under their path prefixes.
+	listeners := make(map[string]*http.ServeMux)
+	listenerWebhooks := make(map[string]*config.Webhook)
+	var addrs []string
+	gatewayConfigs := cfg.GatewayConfigs()
+	for _, gatewayCfg := range gatewayConfigs {
+		// Create an HTTP client with the TLS settings of each destination.
+		client, err := createHTTPClient(gatewayCfg)
+		if err != nil {
+			return fmt.Errorf("error creating HTTP client for region %s: %w", gatewayCfg.Region, err)
+		}
 
-	// Build the address for the server based on the configuration.
-	addr := fmt.Sprintf("%s:%s", cfg.InternalWebServerAddress, cfg.InternalWebServerPort)
+		// Initialize the Livepeer service with the HTTP client and the gateway's configuration.
+		livepeerService, err := createLivepeerService(client, gatewayCfg)
+		if err != nil {
+			return fmt.Errorf("error creating Livepeer service for region %s: %w", gatewayCfg.Region, err)
+		}
+		webhookServer := server.NewEmbeddedWebhookServer(gatewayCfg, client, livepeerService)
+		if coordinationStore != nil {
+			webhookServer.SetCoordination(coordinationStore)
+		}
+		webhookServers = append(webhookServers, webhookServer)
 
-	// Start the server in a separate goroutine to handle requests.
-	go func() {
-		if err := webhookServer.StartServer(addr); err != nil {
-			log.Fatalf("Error starting server: %v", err)
+		addr := gatewayCfg.ListenAddress()
+		if _, exists := listeners[addr]; !exists {
+			listeners[addr] = http.NewServeMux()
+			listenerWebhooks[addr] = gatewayCfg.Webhook
+			addrs = append(addrs, addr)
 		}
-	}()
+		listeners[addr].Handle(gatewayCfg.PathPrefix+"/", webhookServer.Handler())
+	}
 
-	// Run the logic to fetch orchestrators, pipelines, and send test jobs.
-	if err := webhookServer.RunTestJobs(); err != nil {
-		log.Fatalf("Error running test jobs: %v", err)
+	// Start the servers in separate goroutines to handle requests. A server that fails stops the tester,
+	// after the stats and spans are flushed.
+	for _, addr := range addrs {
+		go func(addr string) {
+			if err := server.Serve(addr, listeners[addr], listenerWebhooks[addr]); err != nil {
+				slog.Error("Error starting server", "err", err)
+				shutdown()
+				os.Exit(1)
+			}
+		}(addr)
+	}
+
+	if *serve {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+
+		// Reload the configuration when the file changes or on SIGHUP. Rounds pick it up when they start.
+		hup := make(chan os.Signal, 1)
+		signal.Notify(hup, syscall.SIGHUP)
+		current := cfg
+		go config.Watch(ctx, configLoader, *configFile, *reloadInterval, hup, func(next *config.Config, err error) {
+			if err == nil {
+				err = reloadConfig(current, next, webhookServers, *logLevel, *logFormat)
+			}
+			if err != nil {
+				slog.Error("Configuration reload rejected, keeping the last good configuration", "file", *configFile, "err", err)
+				for _, webhookServer := range webhookServers {
+					webhookServer.RejectReload(err)
+				}
+				return
+			}
+			current = next
+			slog.Info("Configuration reloaded, applied at the start of the next round", "file", *configFile)
+			slog.Debug("Reloaded configuration", "config", next.Redacted())
+		})
+
+		var wg sync.WaitGroup
+		for _, webhookServer := range webhookServers {
+			wg.Add(1)
+			go func(webhookServer *server.EmbeddedWebhookServer) {
+				defer wg.Done()
+				runServe(ctx, webhookServer, *interval)
+			}(webhookServer)
+		}
+		wg.Wait()
+		stop()
+		return nil
+	}
+
+	// Run the logic to fetch orchestrators, pipelines, and send test jobs, for every gateway in parallel.
+	errs := make([]error, len(webhookServers))
+	var wg sync.WaitGroup
+	for i, webhookServer := range webhookServers {
+		wg.Add(1)
+		go func(i int, webhookServer *server.EmbeddedWebhookServer) {
+			defer wg.Done()
+			if err := webhookServer.RunTestJobs(); err != nil {
+				errs[i] = fmt.Errorf("region %s: %w", gatewayConfigs[i].Region, err)
+			}
+		}(i, webhookServer)
+	}
+	wg.Wait()
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("error running test jobs: %w", err)
+	}
+	return nil
+}
+
+// runServe keeps the gateway's tester running until the context is cancelled on SIGINT or SIGTERM. When interval
+// is positive a full round runs immediately and then at every interval; otherwise rounds only run through the
+// admin API. A scheduled round is skipped while another round is running.
+func runServe(ctx context.Context, webhookServer *server.EmbeddedWebhookServer, interval time.Duration) {
+	if interval <= 0 {
+		slog.Info("Serving admin API, test rounds run on demand")
+		<-ctx.Done()
+		webhookServer.CancelRound()
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		err := webhookServer.RunRound(ctx, server.RoundFilter{})
+		if errors.Is(err, server.ErrRoundInProgress) {
+			slog.Warn("Skipping scheduled round", "err", err)
+		} else if err != nil {
+			slog.Error("Error running test jobs", "err", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
 	}
 }
 
-// createHTTPClient creates and returns a new HTTP client with a custom transport configuration.
-// It sets the client to skip certificate verification for TLS and sets a 3-minute timeout for requests.
-func createHTTPClient() *http.Client {
-	tr := &http.Transport{
-		TLSClientConfig: &tls.Config{InsecureSkipVerify: true}, // Skip TLS certificate verification.
+// reloadConfig hands the reloaded configuration of each gateway, with a new HTTP client and Livepeer service,
+// to its server. The flags keep precedence over the configuration file. The reload is rejected when it changes
+// settings that require a restart, or when a client can't be created or the log level is invalid.
+func reloadConfig(current, next *config.Config, webhookServers []*server.EmbeddedWebhookServer, logLevel, logFormat string) error {
+	if logLevel != "" {
+		next.LogLevel = logLevel
 	}
-	return &http.Client{
-		Timeout:   3 * time.Minute, // Set request timeout to 3 minutes.
-		Transport: tr,
+	if logFormat != "" {
+		next.LogFormat = logFormat
 	}
+	if changed := config.ChangesRequiringRestart(current, next); len(changed) > 0 {
+		return fmt.Errorf("changes to %s require a restart", strings.Join(changed, ", "))
+	}
+
+	gatewayConfigs := next.GatewayConfigs()
+	clients := make([]*http.Client, len(gatewayConfigs))
+	livepeerServices := make([]services.LivepeerService, len(gatewayConfigs))
+	for i, gatewayCfg := range gatewayConfigs {
+		client, err := createHTTPClient(gatewayCfg)
+		if err != nil {
+			return fmt.Errorf("region %s: %w", gatewayCfg.Region, err)
+		}
+		clients[i] = client
+		if livepeerServices[i], err = createLivepeerService(client, gatewayCfg); err != nil {
+			return fmt.Errorf("region %s: %w", gatewayCfg.Region, err)
+		}
+	}
+	if err := logging.SetLevel(next.LogLevel); err != nil {
+		return err
+	}
+	// The gateway regions can't change, so the servers are in the same order.
+	for i, gatewayCfg := range gatewayConfigs {
+		webhookServers[i].Reload(gatewayCfg, clients[i], livepeerServices[i])
+	}
+	return nil
+}
+
+// createLivepeerService creates the Livepeer service of a gateway, batching the posted stats when metricsBatch is configured.
+func createLivepeerService(client *http.Client, cfg *config.Config) (services.LivepeerService, error) {
+	if cfg.MetricsBatch != nil {
+		return services.NewBatchingLivepeerService(client, cfg)
+	}
+	return services.NewHTTPLivepeerService(client, cfg), nil
+}
+
+// createHTTPClient creates and returns a new HTTP client using the TLS settings of each destination.
+// Server certificates are verified unless a destination opts out, and requests time out after 3 minutes.
+func createHTTPClient(cfg *config.Config) (*http.Client, error) {
+	return httpclient.New(cfg, 3*time.Minute) // Set request timeout to 3 minutes.
 }
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"livepeer-job-tester/internal/config"
+	"log"
+	"os"
+	"path/filepath"
+)
+
+// main converts a JSON configuration file to YAML, e.g. to add comments or write prompts as
+// multi-line blocks. The YAML is written to -o, or to stdout. References like ${ENV_VAR} are kept.
+// With -check, the converted file is also loaded and validated like the tester would, from the directory of
+// -o or of the input file, so relative includes resolve like they will for the tester.
+func main() {
+	input := flag.String("f", "configs/config.json", "path to the JSON config file")
+	output := flag.String("o", "", "path to the YAML file to write, stdout when empty")
+	check := flag.Bool("check", false, "load and validate the converted configuration, resolving its references")
+	flag.Parse()
+
+	data, err := os.ReadFile(*input)
+	if err != nil {
+		log.Fatalf("Error reading config: %v", err)
+	}
+	converted, err := config.ConvertJSONToYAML(data)
+	if err != nil {
+		log.Fatalf("Error converting config: %v", err)
+	}
+
+	// The config may hold secrets, so only the owner may read the written file, even if it already existed.
+	if *output == "" {
+		fmt.Print(string(converted))
+	} else if err := os.WriteFile(*output, converted, 0o600); err != nil {
+		log.Fatalf("Error writing config: %v", err)
+	} else if err := os.Chmod(*output, 0o600); err != nil {
+		log.Fatalf("Error writing config: %v", err)
+	}
+
+	if *check {
+		if err := checkConverted(converted, *input, *output); err != nil {
+			log.Fatalf("Converted config is invalid: %v", err)
+		}
+		fmt.Fprintln(os.Stderr, "Converted config is valid")
+	}
+}
+
+// checkConverted loads and validates the converted YAML configuration: the output file when written,
+// otherwise a temporary copy next to the input file.
+func checkConverted(converted []byte, input, output string) error {
+	if output != "" {
+		_, err := (&config.YAMLConfigLoader{}).Load(output)
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(input), ".config-check-*.yaml")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(converted); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	_, err = (&config.YAMLConfigLoader{}).Load(tmp.Name())
+	return err
+}
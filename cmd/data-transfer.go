@@ -4,14 +4,16 @@ import (
 	"bytes"
 	"crypto/hmac"
 	"crypto/sha256"
-	"crypto/tls"
 	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"flag"
 	"fmt"
 	"io/ioutil"
+	"livepeer-job-tester/internal/config"
+	"livepeer-job-tester/internal/httpclient"
 	"livepeer-job-tester/internal/types"
+	"log"
 	"net/http"
 	"time"
 )
@@ -21,30 +23,33 @@ func main() {
 	leaderboardURL := flag.String("api", "http://localhost:8080", "Destination Leaderboard API Server URL")
 	gatewayURL := flag.String("gw", "http://localhost:7935", "Livepeer Gateway Cli Endpoint URL")
 	apiSecretKey := flag.String("secret", "your-api-secret-key", "Destination Leaderboard API Secret Key")
+	sourceTLS := newTLSFlags("source-api", "the source Leaderboard API")
+	leaderboardTLS := newTLSFlags("api", "the destination Leaderboard API")
+	gatewayTLS := newTLSFlags("gw", "the Gateway")
 	flag.Parse()
 
 	fmt.Printf("Starting Data Transfer from [%s].  Gateway [%s] and Leaderboard API  [%s] secret key [*****]\n", *sourceLeaderboardURL, *gatewayURL, *leaderboardURL)
 
-	tr := &http.Transport{
-		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
-	}
-	client := &http.Client{
-		Timeout:   120 * time.Second,
-		Transport: tr,
+	// Each destination is called with its own TLS settings.
+	client, err := httpclient.NewForDestinations(120*time.Second,
+		httpclient.Destination{Name: "source-api", Endpoint: *sourceLeaderboardURL, TLS: sourceTLS.settings()},
+		httpclient.Destination{Name: "api", Endpoint: *leaderboardURL, TLS: leaderboardTLS.settings()},
+		httpclient.Destination{Name: "gw", Endpoint: *gatewayURL, TLS: gatewayTLS.settings()},
+	)
+	if err != nil {
+		log.Fatalf("Error creating HTTP client: %v", err)
 	}
 
 	// Fetch all Orchs
 	orchestrators, err := FetchOrchestrators(client, *gatewayURL)
 	if err != nil {
-		fmt.Println("failed to fetch orchs = %v", err)
-
-		return
+		log.Fatalf("Error fetching orchestrators: %v", err)
 	}
 
 	// For each orch, call the Livepeer Prod raw_stats JSON endpoint
 	for _, orch := range orchestrators {
 		url := fmt.Sprintf("%s/api/raw_stats?orchestrator=%s", *sourceLeaderboardURL, orch.Address)
-		resp, err := http.Get(url)
+		resp, err := client.Get(url)
 		if err != nil {
 			fmt.Printf("Error making request for %s: %v\n", orch.Address, err)
 			continue
@@ -80,6 +85,30 @@ func main() {
 	}
 }
 
+// tlsFlags are the TLS settings of one destination, set with flags named after it.
+type tlsFlags struct {
+	caFile, certFile, keyFile *string
+	insecure                  *bool
+}
+
+// newTLSFlags defines the -<prefix>-ca-file, -<prefix>-cert-file, -<prefix>-key-file and -<prefix>-insecure flags.
+func newTLSFlags(prefix, destination string) tlsFlags {
+	return tlsFlags{
+		caFile:   flag.String(prefix+"-ca-file", "", "PEM bundle of additional certificate authorities to trust for "+destination),
+		certFile: flag.String(prefix+"-cert-file", "", "PEM client certificate for mutual TLS with "+destination),
+		keyFile:  flag.String(prefix+"-key-file", "", "PEM client key for mutual TLS with "+destination),
+		insecure: flag.Bool(prefix+"-insecure", false, "skip TLS certificate verification of "+destination+" (traffic can be intercepted)"),
+	}
+}
+
+// settings returns the TLS settings, or nil when no flag is set.
+func (f tlsFlags) settings() *config.TLS {
+	if *f.caFile == "" && *f.certFile == "" && *f.keyFile == "" && !*f.insecure {
+		return nil
+	}
+	return &config.TLS{CAFile: *f.caFile, CertFile: *f.certFile, KeyFile: *f.keyFile, InsecureSkipVerify: *f.insecure}
+}
+
 func FetchOrchestrators(client *http.Client, gatewayUrl string) ([]types.Orchestrator, error) {
 	// Implement the logic to fetch orchestrators
 	url := fmt.Sprintf("%s/registeredOrchestrators", gatewayUrl)
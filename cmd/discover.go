@@ -0,0 +1,96 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"livepeer-job-tester/internal/catalog"
+	"livepeer-job-tester/internal/config"
+	"livepeer-job-tester/internal/httpclient"
+	"livepeer-job-tester/internal/services"
+	"livepeer-job-tester/internal/types"
+	"log"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// main lists the pipeline types advertised by the orchestrators of every configured gateway that have no
+// pipeline configuration, and writes a draft configuration for each of them to -o, or to stdout.
+// Drafts of known Livepeer AI pipelines come from the built-in catalogue; other types get a skeleton
+// to be completed by hand. The drafts are meant to be reviewed, then listed in the include of the configuration.
+func main() {
+	configFile := flag.String("f", "configs/config.json", "path to the config file")
+	profile := flag.String("profile", os.Getenv("JOBTESTER_PROFILE"), "comma-separated profiles of the config file to apply, in order (e.g. smoke)")
+	output := flag.String("o", "", "path to the file to write the draft pipelines to, stdout when empty")
+	format := flag.String("format", "yaml", "format of the draft pipelines (yaml or json)")
+	flag.Parse()
+
+	var profiles []string
+	for _, name := range strings.Split(*profile, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			profiles = append(profiles, name)
+		}
+	}
+	cfg, err := config.NewLoader(*configFile, profiles...).Load(*configFile)
+	if err != nil {
+		log.Fatalf("Error loading config: %v", err)
+	}
+
+	// Fetch the capabilities advertised to every gateway; orchestrators can differ between regions.
+	var capabilities []*types.Pipelines
+	for _, gatewayCfg := range cfg.GatewayConfigs() {
+		client, err := httpclient.New(gatewayCfg, 30*time.Second)
+		if err != nil {
+			log.Fatalf("Error creating HTTP client for region %s: %v", gatewayCfg.Region, err)
+		}
+		pipelines, err := services.NewHTTPLivepeerService(client, gatewayCfg).FetchPipelines()
+		if err != nil {
+			log.Fatalf("Error fetching capabilities for region %s: %v", gatewayCfg.Region, err)
+		}
+		capabilities = append(capabilities, pipelines)
+	}
+
+	discoveries := catalog.Discover(cfg, capabilities...)
+	if len(discoveries) == 0 {
+		fmt.Fprintln(os.Stderr, "Every advertised pipeline type has a pipeline configuration")
+		return
+	}
+	report(discoveries)
+
+	// The drafts must not conflict with the configured pipelines once included.
+	drafts := catalog.Drafts(discoveries)
+	merged := *cfg
+	merged.Pipelines = append(append([]config.Pipeline(nil), cfg.Pipelines...), drafts...)
+	if err := merged.Validate(); err != nil {
+		log.Fatalf("Draft pipelines are invalid: %v", err)
+	}
+
+	out, err := catalog.MarshalDrafts(drafts, *format)
+	if err != nil {
+		log.Fatalf("Error encoding draft pipelines: %v", err)
+	}
+	if *format == "yaml" {
+		header := fmt.Sprintf("# Draft pipelines discovered from the gateway capabilities on %s.\n# Review them before including this file in the configuration.\n", time.Now().UTC().Format(time.DateOnly))
+		out = append([]byte(header), out...)
+	}
+	if *output == "" {
+		fmt.Print(string(out))
+	} else if err := os.WriteFile(*output, out, 0o644); err != nil {
+		log.Fatalf("Error writing draft pipelines: %v", err)
+	}
+}
+
+// report writes the discovered pipeline types to stderr, with the orchestrators and models advertising them.
+func report(discoveries []catalog.Discovery) {
+	w := tabwriter.NewWriter(os.Stderr, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "TYPE\tORCHESTRATORS\tMODELS\tDRAFT")
+	for _, d := range discoveries {
+		draft := "catalogue"
+		if !d.Catalogued {
+			draft = "skeleton, complete by hand"
+		}
+		fmt.Fprintf(w, "%s\t%d\t%s\t%s\n", d.Type, len(d.Orchestrators), strings.Join(d.Models, ", "), draft)
+	}
+	w.Flush()
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"livepeer-job-tester/internal/config"
+	"livepeer-job-tester/internal/coordination"
+	"livepeer-job-tester/internal/logging"
+	"livepeer-job-tester/internal/server"
+	"log/slog"
+	"os"
+)
+
+// main runs the coordinator shared by the testers of several regions: it hands out the orchestrator
+// leases and keeps the round summary of every region. Leases are kept in memory, or in a file with -file
+// so they survive a restart. The token defaults to the JOBTESTER_COORDINATOR_TOKEN environment variable.
+// It exits with status 1 when run fails.
+func main() {
+	if err := run(); err != nil {
+		slog.Error("Coordinator failed", "err", err)
+		os.Exit(1)
+	}
+}
+
+// run runs the coordinator, see main.
+func run() error {
+	addr := flag.String("addr", ":7940", "address to listen on")
+	file := flag.String("file", "", "keep leases and summaries in this JSON file instead of in memory")
+	token := flag.String("token", os.Getenv("JOBTESTER_COORDINATOR_TOKEN"), "bearer token required from the testers")
+	certFile := flag.String("cert-file", "", "PEM server certificate, serves over HTTPS with -key-file")
+	keyFile := flag.String("key-file", "", "PEM server key")
+	logLevel := flag.String("log-level", "info", "log level (debug, info, warn or error)")
+	logFormat := flag.String("log-format", "logfmt", "log format (json or logfmt)")
+	flag.Parse()
+
+	logger, err := logging.New(os.Stderr, *logFormat, *logLevel)
+	if err != nil {
+		return fmt.Errorf("error setting up logging: %w", err)
+	}
+	slog.SetDefault(logger)
+
+	var store coordination.Store = coordination.NewMemoryStore()
+	if *file != "" {
+		store = coordination.NewFileStore(*file)
+	}
+	if *token == "" {
+		slog.Warn("No token configured, any client can take leases")
+	}
+
+	handler := coordination.Handler(store, *token)
+	if err := server.Serve(*addr, handler, &config.Webhook{CertFile: *certFile, KeyFile: *keyFile}); err != nil {
+		return fmt.Errorf("error running coordinator: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"livepeer-job-tester/internal/config"
+	"livepeer-job-tester/internal/leaderboard"
+	"livepeer-job-tester/internal/logging"
+	"livepeer-job-tester/internal/server"
+	"log/slog"
+	"os"
+)
+
+// main runs a Leaderboard API compatible server, so the tester, data-transfer and other tools can run without
+// the external serverless API. It accepts the stats posted by the testers, single or batched, and serves the raw
+// and aggregated stats. Stats are kept in memory, or in a JSON Lines file with -file so they survive a restart.
+// The secret checking the posts defaults to JOBTESTER_METRICS_SECRET, the variable setting the testers' metricsSecret,
+// and is required unless -insecure is set. It exits with status 1 when run fails.
+func main() {
+	if err := run(); err != nil {
+		slog.Error("Leaderboard server failed", "err", err)
+		os.Exit(1)
+	}
+}
+
+// run runs the leaderboard server, see main. The stats file is closed before it returns.
+func run() error {
+	addr := flag.String("addr", ":8080", "address to listen on")
+	file := flag.String("file", "", "keep the stats in this JSON Lines file instead of in memory")
+	secret := flag.String("secret", os.Getenv("JOBTESTER_METRICS_SECRET"), "secret the posted stats are signed with")
+	insecure := flag.Bool("insecure", false, "accept posts signed with an empty secret when no secret is set")
+	certFile := flag.String("cert-file", "", "PEM server certificate, serves over HTTPS with -key-file")
+	keyFile := flag.String("key-file", "", "PEM server key")
+	logLevel := flag.String("log-level", "info", "log level (debug, info, warn or error)")
+	logFormat := flag.String("log-format", "logfmt", "log format (json or logfmt)")
+	flag.Parse()
+
+	logger, err := logging.New(os.Stderr, *logFormat, *logLevel)
+	if err != nil {
+		return fmt.Errorf("error setting up logging: %w", err)
+	}
+	slog.SetDefault(logger)
+
+	if *secret == "" {
+		if !*insecure {
+			return errors.New("no secret configured: set -secret or JOBTESTER_METRICS_SECRET, or -insecure to accept posts signed with an empty secret")
+		}
+		slog.Warn("No secret configured, posts must be signed with an empty secret")
+	}
+
+	var store leaderboard.Store = leaderboard.NewMemoryStore()
+	if *file != "" {
+		fileStore, err := leaderboard.OpenFileStore(*file)
+		if err != nil {
+			return fmt.Errorf("error opening stats file: %w", err)
+		}
+		defer fileStore.Close()
+		store = fileStore
+	}
+	handler := leaderboard.Handler(store, *secret)
+	if err := server.Serve(*addr, handler, &config.Webhook{CertFile: *certFile, KeyFile: *keyFile}); err != nil {
+		return fmt.Errorf("error running leaderboard server: %w", err)
+	}
+	return nil
+}
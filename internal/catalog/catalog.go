@@ -0,0 +1,196 @@
+package catalog
+
+import (
+	"livepeer-job-tester/internal/config"
+)
+
+// entries are the request schemas of the known Livepeer AI pipelines, as draft pipeline configurations:
+// the content type, the request parameters with sensible test values, the asset form fields and a validator
+// of the response. They follow the ai-runner API, with the defaults used by the example configuration.
+var entries = []config.Pipeline{
+	{
+		Name:            "Text to image",
+		Uri:             "text-to-image",
+		CaptureResponse: true,
+		ContentType:     "application/json",
+		Parameters: map[string]interface{}{
+			"prompt":                "a bear",
+			"width":                 512,
+			"height":                512,
+			"num_images_per_prompt": 1,
+			"num_inference_steps":   20,
+			"guidance_scale":        2,
+			"safety_check":          false,
+		},
+		Validator: &config.Validator{Type: config.ValidatorMedia, RequiredFields: []string{"images.0.url"}},
+	},
+	{
+		Name:            "Image to image",
+		Uri:             "image-to-image",
+		CaptureResponse: true,
+		ContentType:     "multipart/form-data",
+		Parameters: map[string]interface{}{
+			"prompt":                "a bear",
+			"strength":              0.8,
+			"guidance_scale":        2,
+			"image_guidance_scale":  2,
+			"num_images_per_prompt": 1,
+			"num_inference_steps":   20,
+			"safety_check":          false,
+		},
+		Assets:    map[string]string{"image": "test-assets/test-image.png"},
+		Validator: &config.Validator{Type: config.ValidatorMedia, RequiredFields: []string{"images.0.url"}},
+	},
+	{
+		Name:            "Image to video",
+		Uri:             "image-to-video",
+		CaptureResponse: true,
+		ContentType:     "multipart/form-data",
+		Parameters: map[string]interface{}{
+			"width":              1024,
+			"height":             576,
+			"fps":                8,
+			"motion_bucket_id":   127,
+			"noise_aug_strength": 0.065,
+		},
+		Assets:    map[string]string{"image": "test-assets/test-image.png"},
+		Timeout:   "180s",
+		Validator: &config.Validator{Type: config.ValidatorMedia, RequiredFields: []string{"images.0.url"}},
+	},
+	{
+		Name:            "Upscale",
+		Uri:             "upscale",
+		CaptureResponse: true,
+		ContentType:     "multipart/form-data",
+		Parameters: map[string]interface{}{
+			"prompt":              "a bear",
+			"num_inference_steps": 20,
+			"safety_check":        false,
+		},
+		Assets:    map[string]string{"image": "test-assets/test-upscale.jpg"},
+		Validator: &config.Validator{Type: config.ValidatorMedia, RequiredFields: []string{"images.0.url"}},
+	},
+	{
+		Name:            "Audio to text",
+		Uri:             "audio-to-text",
+		CaptureResponse: true,
+		ContentType:     "multipart/form-data",
+		Parameters:      map[string]interface{}{},
+		Assets:          map[string]string{"audio": "test-assets/test-audio.mp4"},
+		Validator:       &config.Validator{Type: config.ValidatorJSON, RequiredFields: []string{"text"}},
+	},
+	{
+		Name:            "Segment anything 2",
+		Uri:             "segment-anything-2",
+		CaptureResponse: true,
+		ContentType:     "multipart/form-data",
+		Parameters: map[string]interface{}{
+			"box":              "[380.50, 130.00, 651.50, 479.00]",
+			"multimask_output": true,
+			"return_logits":    true,
+			"normalize_coords": true,
+		},
+		Assets:    map[string]string{"image": "test-assets/test-image.png"},
+		Validator: &config.Validator{Type: config.ValidatorJSON, RequiredFields: []string{"masks", "scores"}},
+	},
+	{
+		Name:            "Llm",
+		Uri:             "llm",
+		CaptureResponse: true,
+		StreamResponse:  true,
+		ContentType:     "multipart/form-data",
+		Parameters: map[string]interface{}{
+			"prompt":     "how many characters are in an ethereum address?",
+			"max_tokens": 256,
+		},
+	},
+	{
+		Name:            "Image to text",
+		Uri:             "image-to-text",
+		CaptureResponse: true,
+		ContentType:     "multipart/form-data",
+		Parameters: map[string]interface{}{
+			"prompt": "alert and ready for danger",
+		},
+		Assets:    map[string]string{"image": "test-assets/test-image.png"},
+		Validator: &config.Validator{Type: config.ValidatorJSON, RequiredFields: []string{"text"}},
+	},
+	{
+		Name:            "Text to speech",
+		Uri:             "text-to-speech",
+		CaptureResponse: true,
+		ContentType:     "application/json",
+		Parameters: map[string]interface{}{
+			"text":        "The quick brown fox jumps over the lazy dog.",
+			"description": "A clear voice with a close recording and almost no background noise.",
+		},
+		Validator: &config.Validator{Type: config.ValidatorMedia, RequiredFields: []string{"audio.url"}},
+	},
+	{
+		Name:        "Live video to video",
+		Uri:         "live-video-to-video",
+		Kind:        config.PipelineKindStream,
+		ContentType: "application/json",
+		Parameters: map[string]interface{}{
+			"prompt": "a watercolor painting",
+		},
+		Stream: &config.StreamConfig{
+			Duration:          "10s",
+			Width:             512,
+			Height:            512,
+			FPS:               30,
+			SegmentDuration:   "1s",
+			FirstFrameTimeout: "60s",
+			IdleTimeout:       "5s",
+		},
+	},
+}
+
+// Lookup returns the catalogue entry of a capability type reported by the gateway, matched by canonical ID
+// against the entries' uri and name like config.FindPipeline. The entry is a copy that can be edited.
+func Lookup(capabilityType string) (config.Pipeline, bool) {
+	catalog := config.Config{Pipelines: entries}
+	pipeline, found := catalog.FindPipeline(capabilityType)
+	if !found {
+		return config.Pipeline{}, false
+	}
+	return clone(*pipeline), true
+}
+
+// Skeleton returns a draft for a capability type missing from the catalogue: a JSON request to the
+// capability's path without parameters, to be completed by hand.
+func Skeleton(capabilityType string) config.Pipeline {
+	return config.Pipeline{
+		Name:            capabilityType,
+		Uri:             capabilityType,
+		CaptureResponse: true,
+		ContentType:     "application/json",
+		Parameters:      map[string]interface{}{},
+	}
+}
+
+// clone returns a copy of the pipeline that shares no maps or slices with it.
+func clone(p config.Pipeline) config.Pipeline {
+	parameters := make(map[string]interface{}, len(p.Parameters))
+	for k, v := range p.Parameters {
+		parameters[k] = v
+	}
+	p.Parameters = parameters
+	if p.Assets != nil {
+		assets := make(map[string]string, len(p.Assets))
+		for k, v := range p.Assets {
+			assets[k] = v
+		}
+		p.Assets = assets
+	}
+	if p.Validator != nil {
+		validator := *p.Validator
+		validator.RequiredFields = append([]string(nil), p.Validator.RequiredFields...)
+		p.Validator = &validator
+	}
+	if p.Stream != nil {
+		stream := *p.Stream
+		p.Stream = &stream
+	}
+	return p
+}
@@ -0,0 +1,226 @@
+package catalog
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"livepeer-job-tester/internal/config"
+	"livepeer-job-tester/internal/types"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Discovery is a pipeline type advertised by the orchestrators that no pipeline of the configuration matches.
+// Catalogued reports whether Draft comes from the catalogue or is a skeleton to be completed by hand.
+type Discovery struct {
+	Type          string          `json:"type"`
+	Orchestrators []string        `json:"orchestrators"`
+	Models        []string        `json:"models"`
+	Catalogued    bool            `json:"catalogued"`
+	Draft         config.Pipeline `json:"draft"`
+}
+
+// Discover returns the pipeline types advertised in the capabilities that have no pipeline configuration,
+// sorted by type, each with the orchestrators and models advertising it and a draft configuration.
+// Types are grouped by canonical ID, so "text-to-image" and "text_to_image" are a single discovery.
+func Discover(cfg *config.Config, capabilities ...*types.Pipelines) []Discovery {
+	type advertised struct {
+		discovery     *Discovery
+		orchestrators map[string]bool
+		models        map[string]bool
+	}
+	byID := make(map[string]*advertised)
+	for _, pipelines := range capabilities {
+		if pipelines == nil {
+			continue
+		}
+		for _, orchestrator := range pipelines.Orchestrators {
+			for _, pipeline := range orchestrator.Pipelines {
+				id := config.CanonicalPipelineID(pipeline.Type)
+				if id == "" {
+					continue
+				}
+				if _, found := cfg.FindPipeline(id); found {
+					continue
+				}
+				a, exists := byID[id]
+				if !exists {
+					a = &advertised{
+						discovery:     &Discovery{Type: pipeline.Type},
+						orchestrators: make(map[string]bool),
+						models:        make(map[string]bool),
+					}
+					byID[id] = a
+				}
+				a.orchestrators[orchestrator.Address] = true
+				for _, model := range pipeline.Models {
+					a.models[model.Name] = true
+				}
+			}
+		}
+	}
+
+	discoveries := make([]Discovery, 0, len(byID))
+	for id, a := range byID {
+		d := a.discovery
+		d.Orchestrators = sortedKeys(a.orchestrators)
+		d.Models = sortedKeys(a.models)
+		d.Draft, d.Catalogued = Lookup(id)
+		if !d.Catalogued {
+			d.Draft = Skeleton(id)
+		}
+		discoveries = append(discoveries, *d)
+	}
+	sort.Slice(discoveries, func(i, j int) bool { return discoveries[i].Type < discoveries[j].Type })
+	return discoveries
+}
+
+// Drafts returns the draft pipeline configurations of the discoveries.
+func Drafts(discoveries []Discovery) []config.Pipeline {
+	drafts := make([]config.Pipeline, 0, len(discoveries))
+	for _, d := range discoveries {
+		drafts = append(drafts, d.Draft)
+	}
+	return drafts
+}
+
+// MarshalDrafts encodes the draft pipelines as a configuration file of the given format, "json" or "yaml",
+// with a single pipelines entry, e.g. to be reviewed and listed in the include of the configuration.
+// Fields left at their zero value are omitted, except the parameters, and fields keep their configuration order.
+func MarshalDrafts(drafts []config.Pipeline, format string) ([]byte, error) {
+	data, err := json.Marshal(map[string][]config.Pipeline{"pipelines": drafts})
+	if err != nil {
+		return nil, fmt.Errorf("[MarshalDrafts] error encoding drafts: %w", err)
+	}
+	// JSON is valid YAML, so decoding it into a node keeps the order of the fields.
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("[MarshalDrafts] error encoding drafts: %w", err)
+	}
+	for _, pipeline := range doc.Content[0].Content[1].Content {
+		prune(pipeline)
+	}
+
+	var out bytes.Buffer
+	if err := writeJSON(&out, doc.Content[0]); err != nil {
+		return nil, fmt.Errorf("[MarshalDrafts] error encoding drafts: %w", err)
+	}
+	switch format {
+	case "json":
+		var indented bytes.Buffer
+		if err := json.Indent(&indented, out.Bytes(), "", "  "); err != nil {
+			return nil, fmt.Errorf("[MarshalDrafts] error encoding drafts: %w", err)
+		}
+		indented.WriteByte('\n')
+		return indented.Bytes(), nil
+	case "yaml":
+		converted, err := config.ConvertJSONToYAML(out.Bytes())
+		if err != nil {
+			return nil, fmt.Errorf("[MarshalDrafts] %w", err)
+		}
+		return converted, nil
+	default:
+		return nil, fmt.Errorf("[MarshalDrafts] unknown format %q, expected json or yaml", format)
+	}
+}
+
+// prune removes the fields of the mapping node left at their zero value: null, "", 0, false and empty
+// collections, recursively. Parameters are request values and are kept as they are.
+func prune(node *yaml.Node) {
+	if node.Kind != yaml.MappingNode {
+		return
+	}
+	content := node.Content[:0]
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		if key.Value != "parameters" {
+			prune(value)
+			if isZero(value) {
+				continue
+			}
+		}
+		content = append(content, key, value)
+	}
+	node.Content = content
+}
+
+// isZero reports whether the node holds the zero value of its type.
+func isZero(node *yaml.Node) bool {
+	switch node.Kind {
+	case yaml.MappingNode, yaml.SequenceNode:
+		return len(node.Content) == 0
+	case yaml.ScalarNode:
+		switch node.Tag {
+		case "!!null":
+			return true
+		case "!!str":
+			return node.Value == ""
+		case "!!bool":
+			return node.Value == "false"
+		case "!!int", "!!float":
+			return node.Value == "0"
+		}
+	}
+	return false
+}
+
+// writeJSON writes a node decoded from JSON back as compact JSON, keeping the order of the fields.
+func writeJSON(out *bytes.Buffer, node *yaml.Node) error {
+	switch node.Kind {
+	case yaml.MappingNode:
+		out.WriteByte('{')
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if i > 0 {
+				out.WriteByte(',')
+			}
+			key, err := json.Marshal(node.Content[i].Value)
+			if err != nil {
+				return err
+			}
+			out.Write(key)
+			out.WriteByte(':')
+			if err := writeJSON(out, node.Content[i+1]); err != nil {
+				return err
+			}
+		}
+		out.WriteByte('}')
+	case yaml.SequenceNode:
+		out.WriteByte('[')
+		for i, child := range node.Content {
+			if i > 0 {
+				out.WriteByte(',')
+			}
+			if err := writeJSON(out, child); err != nil {
+				return err
+			}
+		}
+		out.WriteByte(']')
+	case yaml.ScalarNode:
+		if node.Tag != "!!str" {
+			// Numbers, booleans and null are written as they were in the JSON document.
+			out.WriteString(node.Value)
+			return nil
+		}
+		value, err := json.Marshal(node.Value)
+		if err != nil {
+			return err
+		}
+		out.Write(value)
+	default:
+		return fmt.Errorf("unexpected YAML node kind %d", node.Kind)
+	}
+	return nil
+}
+
+// sortedKeys returns the non-empty keys of the set, sorted.
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		if key != "" {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
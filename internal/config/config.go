@@ -3,35 +3,600 @@ package config
 import (
 	"encoding/json"
 	"fmt"
-	"io/ioutil"
-	"os"
+	"net"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
 )
 
-// Config represents the configuration data loaded from the JSON file.
-// It includes settings for the region, job type, internal server,
-// metrics API, broadcaster endpoints, and a list of pipelines.
+// Config represents the tester configuration, loaded from a JSON, YAML or TOML file.
+// It includes settings for the region, job type, internal server, metrics API, broadcaster endpoints
+// and the list of pipelines, along with the optional features below.
 type Config struct {
 	Region                   string     `json:"region"`
 	JobType                  string     `json:"jobType"`
 	InternalWebServerPort    string     `json:"internalWebServerPort"`
 	InternalWebServerAddress string     `json:"internalWebServerAddress"`
+	PathPrefix               string     `json:"pathPrefix"` // Serves the embedded web server under a path.
 	MetricsApiEndpoint       string     `json:"metricsApiEndpoint"`
-	MetricsSecret            string     `json:"metricsSecret"`
+	MetricsSecret            string     `json:"metricsSecret" secret:"true"`
 	BroadcasterJobEndpoint   string     `json:"broadcasterJobEndpoint"`
 	BroadcasterCliEndpoint   string     `json:"broadcasterCliEndpoint"`
-	BroadcasterRequestToken  string     `json:"broadcasterRequestToken"`
+	BroadcasterRequestToken  string     `json:"broadcasterRequestToken" secret:"true"`
+	AdminToken               string     `json:"adminToken" secret:"true"` // Enables the admin API of the embedded web server.
+	LogFormat                string     `json:"logFormat"`                // "json" or "logfmt".
+	LogLevel                 string     `json:"logLevel"`                 // "debug", "info", "warn" or "error".
 	Pipelines                []Pipeline `json:"pipelines"`
+
+	OrchestratorVerification *OrchestratorVerification `json:"orchestratorVerification"` // Checks which orchestrator served each job.
+	Tracing                  *Tracing                  `json:"tracing"`                  // Exports OpenTelemetry traces.
+	TLS                      *TLSSettings              `json:"tls"`                      // Certificate verification per destination.
+	Webhook                  *Webhook                  `json:"webhook"`                  // Secures the embedded web server.
+	Gateways                 []Gateway                 `json:"gateways"`                 // Several gateways to test from, see Gateway.
+	Coordination             *Coordination             `json:"coordination"`             // Shares the test schedule with the testers of other regions.
+	Filter                   *Filter                   `json:"filter"`                   // Restricts every round, e.g. in a smoke test profile.
+	MetricsBatch             *MetricsBatch             `json:"metricsBatch"`             // Posts the stats to the Leaderboard API in batches.
+	OpenAPISpec              string                    `json:"openapiSpec"`              // AI runner OpenAPI spec the pipeline parameters are checked against.
+}
+
+// Gateway is one of several gateways driven by the tester. Its rounds run in parallel with the other
+// gateways', and its stats are tagged with its region. Empty fields inherit the top-level configuration.
+// A gateway serves its webhook, dashboard and admin API on its own listener (InternalWebServerAddress and
+// InternalWebServerPort) or under PathPrefix on a shared one, e.g. "/lax" serves the webhook at /lax/orchestrators.
+// Webhook and TLS replace the top-level settings; of TLS only the gatewayCli and gatewayJob settings are used.
+type Gateway struct {
+	Region                   string       `json:"region"`
+	BroadcasterJobEndpoint   string       `json:"broadcasterJobEndpoint"`
+	BroadcasterCliEndpoint   string       `json:"broadcasterCliEndpoint"`
+	BroadcasterRequestToken  string       `json:"broadcasterRequestToken" secret:"true"`
+	InternalWebServerAddress string       `json:"internalWebServerAddress"`
+	InternalWebServerPort    string       `json:"internalWebServerPort"`
+	PathPrefix               string       `json:"pathPrefix"`
+	Webhook                  *Webhook     `json:"webhook"`
+	TLS                      *TLSSettings `json:"tls"`
+}
+
+// GatewayConfigs returns the configuration of each gateway: the top-level configuration with the
+// gateway's settings applied. Without gateways it returns the configuration itself.
+func (c *Config) GatewayConfigs() []*Config {
+	if len(c.Gateways) == 0 {
+		return []*Config{c}
+	}
+	configs := make([]*Config, 0, len(c.Gateways))
+	for _, gateway := range c.Gateways {
+		gc := *c
+		gc.Gateways = nil
+		gc.Region = gateway.Region
+		gc.PathPrefix = gateway.PathPrefix
+		if gateway.BroadcasterJobEndpoint != "" {
+			gc.BroadcasterJobEndpoint = gateway.BroadcasterJobEndpoint
+		}
+		if gateway.BroadcasterCliEndpoint != "" {
+			gc.BroadcasterCliEndpoint = gateway.BroadcasterCliEndpoint
+		}
+		if gateway.BroadcasterRequestToken != "" {
+			gc.BroadcasterRequestToken = gateway.BroadcasterRequestToken
+		}
+		if gateway.InternalWebServerAddress != "" {
+			gc.InternalWebServerAddress = gateway.InternalWebServerAddress
+		}
+		if gateway.InternalWebServerPort != "" {
+			gc.InternalWebServerPort = gateway.InternalWebServerPort
+		}
+		if gateway.Webhook != nil {
+			gc.Webhook = gateway.Webhook
+		}
+		if gateway.TLS != nil {
+			tls := TLSSettings{GatewayCli: gateway.TLS.GatewayCli, GatewayJob: gateway.TLS.GatewayJob}
+			if c.TLS != nil {
+				tls.MetricsApi = c.TLS.MetricsApi
+			}
+			gc.TLS = &tls
+		}
+		configs = append(configs, &gc)
+	}
+	return configs
+}
+
+// ListenAddress returns the address of the embedded web server.
+func (c *Config) ListenAddress() string {
+	return fmt.Sprintf("%s:%s", c.InternalWebServerAddress, c.InternalWebServerPort)
+}
+
+// Filter restricts test rounds to some orchestrators (eth address or service URI), pipelines (canonical ID)
+// and models. An empty list matches everything. Rounds started through the admin API are restricted by both filters.
+type Filter struct {
+	Orchestrators []string `json:"orchestrators"`
+	Pipelines     []string `json:"pipelines"`
+	Models        []string `json:"models"`
+}
+
+// Coordination shares the test schedule between the testers of several regions, so no two regions test
+// an orchestrator at once. The testers take leases on orchestrators from a store: the JSON file at LockFile,
+// shared by the testers, or the job-coordinator at CoordinatorURL, authenticated with Token.
+// Regions lists every coordinated region in the same order for all testers; each region starts its round at a
+// different point of the orchestrator list. LeaseTTL (default "15m") bounds how long a lease outlives a tester
+// that stopped renewing it, and WaitTimeout (default "30m") how long a round waits for orchestrators leased by
+// other regions before skipping them.
+type Coordination struct {
+	LockFile       string   `json:"lockFile"`
+	CoordinatorURL string   `json:"coordinatorUrl"`
+	Token          string   `json:"token" secret:"true"`
+	Regions        []string `json:"regions"`
+	LeaseTTL       string   `json:"leaseTtl"`
+	WaitTimeout    string   `json:"waitTimeout"`
+}
+
+// LeaseDuration parses LeaseTTL, 15 minutes when empty.
+func (c *Coordination) LeaseDuration() (time.Duration, error) {
+	return parseDefaultDuration("coordination leaseTtl", c.LeaseTTL, 15*time.Minute)
+}
+
+// WaitDuration parses WaitTimeout, 30 minutes when empty.
+func (c *Coordination) WaitDuration() (time.Duration, error) {
+	return parseDefaultDuration("coordination waitTimeout", c.WaitTimeout, 30*time.Minute)
+}
+
+// MetricsBatch groups the stats posted to the Leaderboard API and sends each batch as a JSON array to Endpoint,
+// a bulk endpoint like "https://leaderboard/api/post_stats_batch", signed like a single post. A batch is sent once
+// it holds MaxSize records, MaxWait after its first record or at the end of the round, whichever comes first.
+// Servers without the bulk endpoint are sent single posts to metricsApiEndpoint instead.
+type MetricsBatch struct {
+	Endpoint string `json:"endpoint"`
+	MaxSize  int    `json:"maxSize"`
+	MaxWait  string `json:"maxWait"`
+}
+
+// BatchSize returns MaxSize, 50 when unset.
+func (b *MetricsBatch) BatchSize() int {
+	if b.MaxSize <= 0 {
+		return 50
+	}
+	return b.MaxSize
+}
+
+// WaitDuration parses MaxWait, 10 seconds when empty.
+func (b *MetricsBatch) WaitDuration() (time.Duration, error) {
+	return parseDefaultDuration("metricsBatch maxWait", b.MaxWait, 10*time.Second)
+}
+
+// parseDefaultDuration parses a positive duration setting, returning the default when it is empty.
+func parseDefaultDuration(name, value string, defaultValue time.Duration) (time.Duration, error) {
+	if value == "" {
+		return defaultValue, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("%s must be a positive duration, got %q", name, value)
+	}
+	return d, nil
+}
+
+// Webhook secures the Orch Webhook URL (/orchestrators) of the embedded web server. Every configured check must pass:
+// Secret must be sent as the "secret" query parameter, Token as a bearer token, and the client IP must be in
+// AllowedIPs (IPs or CIDRs). CertFile and KeyFile serve the embedded web server over HTTPS.
+type Webhook struct {
+	Secret     string   `json:"secret" secret:"true"`
+	Token      string   `json:"token" secret:"true"`
+	AllowedIPs []string `json:"allowedIPs"`
+	CertFile   string   `json:"certFile"`
+	KeyFile    string   `json:"keyFile"`
+}
+
+// AllowedNetworks parses AllowedIPs into networks. A plain IP is a network of a single address.
+func (w *Webhook) AllowedNetworks() ([]*net.IPNet, error) {
+	networks := make([]*net.IPNet, 0, len(w.AllowedIPs))
+	for _, entry := range w.AllowedIPs {
+		if !strings.Contains(entry, "/") {
+			ip := net.ParseIP(entry)
+			if ip == nil {
+				return nil, fmt.Errorf("webhook allowedIPs has an invalid IP %q", entry)
+			}
+			bits := 128
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 32
+			}
+			networks = append(networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, network, err := net.ParseCIDR(entry)
+		if err != nil {
+			return nil, fmt.Errorf("webhook allowedIPs has an invalid CIDR %q", entry)
+		}
+		networks = append(networks, network)
+	}
+	return networks, nil
+}
+
+// TLSSettings holds the TLS configuration of each outbound destination: the gateway CLI endpoint,
+// the gateway job endpoint and the metrics API. Destinations without configuration verify server
+// certificates against the system roots.
+type TLSSettings struct {
+	GatewayCli *TLS `json:"gatewayCli"`
+	GatewayJob *TLS `json:"gatewayJob"`
+	MetricsApi *TLS `json:"metricsApi"`
+}
+
+// TLS configures the TLS connections to one destination. CAFile is a PEM bundle of the certificate
+// authorities trusted in addition to the system roots. CertFile and KeyFile are the PEM client certificate
+// and key for mutual TLS. InsecureSkipVerify disables server certificate verification.
+type TLS struct {
+	CAFile             string `json:"caFile"`
+	CertFile           string `json:"certFile"`
+	KeyFile            string `json:"keyFile"`
+	InsecureSkipVerify bool   `json:"insecureSkipVerify"`
+}
+
+// Tracing configures the export of OpenTelemetry traces over OTLP/HTTP.
+// Endpoint is the collector's host:port (e.g. "localhost:4318") or URL. Insecure sends traces over plain HTTP,
+// Headers are added to every export request and ServiceName defaults to "ai-job-tester".
+type Tracing struct {
+	Endpoint    string            `json:"endpoint"`
+	Insecure    bool              `json:"insecure"`
+	Headers     map[string]string `json:"headers" secret:"true"`
+	ServiceName string            `json:"serviceName"`
+}
+
+// OrchestratorVerification configures how the tester verifies which orchestrator actually served a job.
+// Header names a gateway response header carrying the orchestrator service URI or address.
+// DebugEndpoint is a gateway URL, with "{pipeline}" and "{model}" placeholders, returning
+// {"orchestrator": "<service uri or address>"} for the last job. When Required is set, jobs
+// that can't be verified are treated like mismatches.
+type OrchestratorVerification struct {
+	Header        string `json:"header"`
+	DebugEndpoint string `json:"debugEndpoint"`
+	Required      bool   `json:"required"`
 }
 
 // Pipeline represents a data processing pipeline configuration.
 // It includes the name, URI, whether to capture responses,
 // the content type, and additional parameters for the pipeline.
+// Samples and SampleInterval control how many requests make up one job
+// and how long to wait between them. Assets, Timeout and Validator are the
+// defaults for every scenario; a pipeline without scenarios runs a single job.
+// ModelParameters holds per-model overrides keyed by model name, glob ("*Lightning*")
+// or regular expression ("re:^meta-llama/.*"), merged over the scenario parameters.
+// Aliases lists extra capability types reported by the gateway that map to this pipeline.
+// StreamResponse requests a server-sent event (SSE) reply, as supported by the llm pipeline.
 type Pipeline struct {
-	Name            string                 `json:"name"`
-	Uri             string                 `json:"uri"`
-	CaptureResponse bool                   `json:"capture_response"`
-	ContentType     string                 `json:"contentType"`
-	Parameters      map[string]interface{} `json:"parameters"`
+	Name            string                            `json:"name"`
+	Uri             string                            `json:"uri"`
+	CaptureResponse bool                              `json:"capture_response"`
+	ContentType     string                            `json:"contentType"`
+	Parameters      map[string]interface{}            `json:"parameters"`
+	Samples         int                               `json:"samples"`
+	SampleInterval  string                            `json:"sample_interval"`
+	Assets          map[string]string                 `json:"assets"`
+	Timeout         string                            `json:"timeout"`
+	Validator       *Validator                        `json:"validator"`
+	Scenarios       []Scenario                        `json:"scenarios"`
+	ModelParameters map[string]map[string]interface{} `json:"model_parameters"`
+	Aliases         []string                          `json:"aliases"`
+	Kind            string                            `json:"kind"`
+	Stream          *StreamConfig                     `json:"stream"`
+	StreamResponse  bool                              `json:"stream_response"`
+}
+
+const (
+	// PipelineKindRequest is a request/response pipeline posted to the gateway job endpoint (the default).
+	PipelineKindRequest = "request"
+	// PipelineKindStream is a live video pipeline tested through a streaming session.
+	PipelineKindStream = "stream"
+)
+
+// StreamConfig configures a live video pipeline (kind "stream").
+// StartPath and StreamPath are relative to the gateway job endpoint, with "{stream}" replaced by a
+// generated stream name. The input video is the MPEG-TS Asset, or a synthetic test pattern of
+// Width x Height at FPS for Duration when no asset is set.
+type StreamConfig struct {
+	StartPath         string `json:"start_path"`
+	StreamPath        string `json:"stream_path"`
+	Asset             string `json:"asset"`
+	Duration          string `json:"duration"`
+	Width             int    `json:"width"`
+	Height            int    `json:"height"`
+	FPS               int    `json:"fps"`
+	SegmentDuration   string `json:"segment_duration"`
+	FirstFrameTimeout string `json:"first_frame_timeout"`
+	IdleTimeout       string `json:"idle_timeout"`
+}
+
+// StreamSettings holds the parsed settings of a StreamConfig with defaults applied.
+type StreamSettings struct {
+	StartPath         string
+	StreamPath        string
+	Asset             string
+	Duration          time.Duration
+	Width             int
+	Height            int
+	FPS               int
+	SegmentDuration   time.Duration
+	FirstFrameTimeout time.Duration
+	IdleTimeout       time.Duration
+}
+
+// IsStream reports whether the pipeline is tested through a live video streaming session.
+func (p *Pipeline) IsStream() bool {
+	return p.Kind == PipelineKindStream
+}
+
+// StreamSettings parses the stream configuration of the pipeline and applies the defaults:
+// the go-livepeer live-video-to-video paths, a 10s 512x512 30fps synthetic video,
+// 1s segments, a 60s first frame timeout and a 5s idle timeout.
+func (p *Pipeline) StreamSettings() (*StreamSettings, error) {
+	sc := StreamConfig{}
+	if p.Stream != nil {
+		sc = *p.Stream
+	}
+	settings := &StreamSettings{
+		StartPath:  sc.StartPath,
+		StreamPath: sc.StreamPath,
+		Asset:      sc.Asset,
+		Width:      sc.Width,
+		Height:     sc.Height,
+		FPS:        sc.FPS,
+	}
+	if settings.StartPath == "" {
+		settings.StartPath = "live/video-to-video/{stream}/start"
+	}
+	if settings.StreamPath == "" {
+		settings.StreamPath = "live/video-to-video/{stream}"
+	}
+	if settings.Width <= 0 {
+		settings.Width = 512
+	}
+	if settings.Height <= 0 {
+		settings.Height = 512
+	}
+	if settings.FPS <= 0 {
+		settings.FPS = 30
+	}
+
+	durations := []struct {
+		name   string
+		value  string
+		def    time.Duration
+		target *time.Duration
+	}{
+		{"duration", sc.Duration, 10 * time.Second, &settings.Duration},
+		{"segment_duration", sc.SegmentDuration, time.Second, &settings.SegmentDuration},
+		{"first_frame_timeout", sc.FirstFrameTimeout, 60 * time.Second, &settings.FirstFrameTimeout},
+		{"idle_timeout", sc.IdleTimeout, 5 * time.Second, &settings.IdleTimeout},
+	}
+	for _, d := range durations {
+		*d.target = d.def
+		if d.value == "" {
+			continue
+		}
+		parsed, err := time.ParseDuration(d.value)
+		if err != nil || parsed <= 0 {
+			return nil, fmt.Errorf("[Pipeline::StreamSettings] invalid stream %s %q for pipeline %s", d.name, d.value, p.Name)
+		}
+		*d.target = parsed
+	}
+	return settings, nil
+}
+
+// Scenario represents a named variant of a pipeline job, e.g. "1024x1024-50-steps".
+// Its parameters and assets are merged over the pipeline's, while a non-empty
+// timeout or validator replaces the pipeline's.
+type Scenario struct {
+	Name       string                 `json:"name"`
+	Parameters map[string]interface{} `json:"parameters"`
+	Assets     map[string]string      `json:"assets"`
+	Timeout    string                 `json:"timeout"`
+	Validator  *Validator             `json:"validator"`
+}
+
+// Validator describes how a successful (2xx) job response is checked.
+// Type "status" (the default) only checks the status code, while "json" also requires
+// the body to be a JSON object containing every field listed in RequiredFields.
+// Type "media" additionally downloads every generated image, video or audio "url" in the
+// response and checks it against the requested parameters; blank or single colour images
+// fail unless AllowBlank is set.
+type Validator struct {
+	Type           string   `json:"type"`
+	RequiredFields []string `json:"required_fields"`
+	AllowBlank     bool     `json:"allow_blank"`
+}
+
+const (
+	// ValidatorStatus only checks the response status code (the default).
+	ValidatorStatus = "status"
+	// ValidatorJSON requires the body to be a JSON object with the required fields.
+	ValidatorJSON = "json"
+	// ValidatorMedia is ValidatorJSON plus inspection of the generated media.
+	ValidatorMedia = "media"
+)
+
+// FindPipeline returns the pipeline matching a capability type reported by the gateway.
+// The capability type is compared by canonical ID against each pipeline's uri, name and aliases,
+// so "text-to-image", "Text to image" and "text_to_image" all match the same pipeline.
+func (c *Config) FindPipeline(capabilityType string) (*Pipeline, bool) {
+	id := CanonicalPipelineID(capabilityType)
+	for i := range c.Pipelines {
+		for _, key := range c.Pipelines[i].MatchKeys() {
+			if key == id {
+				return &c.Pipelines[i], true
+			}
+		}
+	}
+	return nil, false
+}
+
+// CanonicalPipelineID normalizes a pipeline name, uri or capability type into a canonical ID:
+// lower case words separated by single hyphens, e.g. "Text to image" becomes "text-to-image".
+func CanonicalPipelineID(name string) string {
+	fields := strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+		return r == ' ' || r == '-' || r == '_' || r == '/'
+	})
+	return strings.Join(fields, "-")
+}
+
+// MatchKeys returns the unique canonical IDs a capability type is matched against:
+// the pipeline uri, its name and each of its aliases.
+func (p *Pipeline) MatchKeys() []string {
+	var keys []string
+	seen := make(map[string]bool)
+	for _, name := range append([]string{p.Uri, p.Name}, p.Aliases...) {
+		id := CanonicalPipelineID(name)
+		if id != "" && !seen[id] {
+			seen[id] = true
+			keys = append(keys, id)
+		}
+	}
+	return keys
+}
+
+// ResolvedScenarios returns the scenarios of the pipeline with the pipeline defaults merged in.
+// A pipeline without scenarios resolves to a single unnamed scenario built from its defaults.
+func (p *Pipeline) ResolvedScenarios() []Scenario {
+	if len(p.Scenarios) == 0 {
+		return []Scenario{{
+			Parameters: p.Parameters,
+			Assets:     p.Assets,
+			Timeout:    p.Timeout,
+			Validator:  p.Validator,
+		}}
+	}
+
+	resolved := make([]Scenario, 0, len(p.Scenarios))
+	for _, sc := range p.Scenarios {
+		merged := Scenario{
+			Name:       sc.Name,
+			Parameters: make(map[string]interface{}),
+			Assets:     make(map[string]string),
+			Timeout:    p.Timeout,
+			Validator:  p.Validator,
+		}
+		for k, v := range p.Parameters {
+			merged.Parameters[k] = v
+		}
+		for k, v := range sc.Parameters {
+			merged.Parameters[k] = v
+		}
+		for k, v := range p.Assets {
+			merged.Assets[k] = v
+		}
+		for k, v := range sc.Assets {
+			merged.Assets[k] = v
+		}
+		if sc.Timeout != "" {
+			merged.Timeout = sc.Timeout
+		}
+		if sc.Validator != nil {
+			merged.Validator = sc.Validator
+		}
+		resolved = append(resolved, merged)
+	}
+	return resolved
+}
+
+// ModelOverrides returns the parameter overrides of every ModelParameters key matching the model.
+// Glob and regular expression keys are applied in alphabetical order, and an exact model name
+// key is applied last so it always wins. It returns an error if a key is an invalid expression.
+func (p *Pipeline) ModelOverrides(model string) (map[string]interface{}, error) {
+	keys := make([]string, 0, len(p.ModelParameters))
+	for key := range p.ModelParameters {
+		if key != model {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	if _, exact := p.ModelParameters[model]; exact {
+		keys = append(keys, model)
+	}
+
+	overrides := make(map[string]interface{})
+	for _, key := range keys {
+		matched, err := matchModel(key, model)
+		if err != nil {
+			return nil, fmt.Errorf("[Pipeline::ModelOverrides] invalid model_parameters key %q for pipeline %s: %w", key, p.Name, err)
+		}
+		if !matched {
+			continue
+		}
+		for k, v := range p.ModelParameters[key] {
+			overrides[k] = v
+		}
+	}
+	return overrides, nil
+}
+
+// modelPatterns caches the compiled ModelParameters keys, so each key is compiled once.
+var modelPatterns sync.Map
+
+// compileModelPattern compiles a ModelParameters key. Keys prefixed with "re:" are regular expressions;
+// other keys are globs where "*" matches any characters (including "/") and "?" matches a single character.
+func compileModelPattern(pattern string) (*regexp.Regexp, error) {
+	if re, ok := modelPatterns.Load(pattern); ok {
+		return re.(*regexp.Regexp), nil
+	}
+	expr, isRegexp := strings.CutPrefix(pattern, "re:")
+	if !isRegexp {
+		expr = regexp.QuoteMeta(pattern)
+		expr = strings.ReplaceAll(expr, `\*`, ".*")
+		expr = strings.ReplaceAll(expr, `\?`, ".")
+		expr = "^" + expr + "$"
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, err
+	}
+	modelPatterns.Store(pattern, re)
+	return re, nil
+}
+
+// matchModel reports whether a model name matches a ModelParameters key, see compileModelPattern.
+func matchModel(pattern, model string) (bool, error) {
+	re, err := compileModelPattern(pattern)
+	if err != nil {
+		return false, err
+	}
+	return re.MatchString(model), nil
+}
+
+// TimeoutDuration parses Timeout (e.g. "90s"), which must be positive, and returns the per-request timeout.
+// An empty timeout returns zero, meaning only the HTTP client timeout applies.
+func (s *Scenario) TimeoutDuration() (time.Duration, error) {
+	if s.Timeout == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(s.Timeout)
+	if err != nil {
+		return 0, fmt.Errorf("[Scenario::TimeoutDuration] invalid timeout %q for scenario %q: %w", s.Timeout, s.Name, err)
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("[Scenario::TimeoutDuration] timeout %q for scenario %q must be positive", s.Timeout, s.Name)
+	}
+	return d, nil
+}
+
+// SampleCount returns the number of requests to send per job.
+// Pipelines without a positive sample count send a single request.
+func (p *Pipeline) SampleCount() int {
+	if p.Samples < 1 {
+		return 1
+	}
+	return p.Samples
+}
+
+// SampleSpacing parses SampleInterval (e.g. "500ms", "2s") and returns the delay between samples.
+// An empty interval means samples are sent back to back.
+func (p *Pipeline) SampleSpacing() (time.Duration, error) {
+	if p.SampleInterval == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(p.SampleInterval)
+	if err != nil {
+		return 0, fmt.Errorf("[Pipeline::SampleSpacing] invalid sample_interval %q for pipeline %s: %w", p.SampleInterval, p.Name, err)
+	}
+	if d < 0 {
+		return 0, fmt.Errorf("[Pipeline::SampleSpacing] negative sample_interval %q for pipeline %s", p.SampleInterval, p.Name)
+	}
+	return d, nil
 }
 
 // Loader defines the interface for loading a configuration from a file.
@@ -44,31 +609,42 @@ type Loader interface {
 
 // JSONConfigLoader is an implementation of Loader that loads
 // configuration data from a JSON file.
-type JSONConfigLoader struct{}
+// Profiles are the names of the profiles applied over the configuration, in order.
+type JSONConfigLoader struct {
+	Profiles []string
+}
 
-// Load reads the configuration from the specified JSON file.
+// Load reads the configuration from the specified JSON file, merged with its includes and profiles.
 // It returns the loaded Config struct or an error if the file
 // cannot be opened, read, or parsed correctly.
 func (l *JSONConfigLoader) Load(filePath string) (*Config, error) {
-	// Open the JSON file
-	file, err := os.Open(filePath)
+	raw, err := loadLayers(filePath, decodeJSON, l.Profiles)
 	if err != nil {
-		return nil, fmt.Errorf("[JSONConfigLoader::LoadConfig] error opening JSON file: %w", err)
+		return nil, fmt.Errorf("[JSONConfigLoader::LoadConfig] %w", err)
 	}
-	defer file.Close()
 
-	// Read the file contents
-	byteValue, err := ioutil.ReadAll(file)
+	config, err := decodeConfig(raw)
 	if err != nil {
-		return nil, fmt.Errorf("[JSONConfigLoader::LoadConfig] error reading JSON file: %w", err)
+		return nil, fmt.Errorf("[JSONConfigLoader::LoadConfig] %w", err)
 	}
+	return config, nil
+}
 
-	// Unmarshal the JSON data into the Config struct
-	var config Config
-	err = json.Unmarshal(byteValue, &config)
+// decodeConfig applies the environment overrides to the raw configuration, resolves its ${ENV_VAR} and file:
+// references, and decodes it into a validated Config. The raw configuration may come from any file format.
+func decodeConfig(raw map[string]interface{}) (*Config, error) {
+	data, err := resolveConfig(raw)
 	if err != nil {
-		return nil, fmt.Errorf("[JSONConfigLoader::LoadConfig] error unmarshalling JSON: %w", err)
+		return nil, fmt.Errorf("error resolving configuration: %w", err)
 	}
 
+	var config Config
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("error decoding configuration: %w", err)
+	}
+
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
 	return &config, nil
 }
@@ -0,0 +1,76 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ConvertJSONToYAML converts a JSON configuration file to YAML, keeping the order of the keys.
+// References like ${ENV_VAR} and file: are kept as they are, not resolved. Strings spanning several
+// lines become literal blocks. The conversion is checked by decoding both documents and comparing them.
+func ConvertJSONToYAML(data []byte) ([]byte, error) {
+	// JSON is valid YAML, so decoding it into a node keeps the order of the keys.
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("[ConvertJSONToYAML] error parsing JSON: %w", err)
+	}
+	if doc.Kind != yaml.DocumentNode || len(doc.Content) != 1 || doc.Content[0].Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("[ConvertJSONToYAML] the configuration must be a JSON object")
+	}
+	blockStyle(&doc)
+
+	var out bytes.Buffer
+	encoder := yaml.NewEncoder(&out)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&doc); err != nil {
+		return nil, fmt.Errorf("[ConvertJSONToYAML] error encoding YAML: %w", err)
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, fmt.Errorf("[ConvertJSONToYAML] error encoding YAML: %w", err)
+	}
+
+	var fromJSON, fromYAML interface{}
+	if err := json.Unmarshal(data, &fromJSON); err != nil {
+		return nil, fmt.Errorf("[ConvertJSONToYAML] error parsing JSON: %w", err)
+	}
+	if err := yaml.Unmarshal(out.Bytes(), &fromYAML); err != nil {
+		return nil, fmt.Errorf("[ConvertJSONToYAML] error checking YAML: %w", err)
+	}
+	// Round trip the YAML document through JSON, so both sides have the same types.
+	normalized, err := json.Marshal(fromYAML)
+	if err != nil {
+		return nil, fmt.Errorf("[ConvertJSONToYAML] error checking YAML: %w", err)
+	}
+	if err := json.Unmarshal(normalized, &fromYAML); err != nil {
+		return nil, fmt.Errorf("[ConvertJSONToYAML] error checking YAML: %w", err)
+	}
+	if !reflect.DeepEqual(fromJSON, fromYAML) {
+		return nil, fmt.Errorf("[ConvertJSONToYAML] the YAML document doesn't match the JSON configuration")
+	}
+	return out.Bytes(), nil
+}
+
+// blockStyle switches the node and its children from the JSON flow style to the YAML block style.
+// Strings are unquoted where YAML allows it, and strings with line breaks become literal blocks.
+func blockStyle(node *yaml.Node) {
+	switch node.Kind {
+	case yaml.ScalarNode:
+		node.Style = 0
+		if node.Tag == "!!str" && strings.Contains(node.Value, "\n") {
+			node.Style = yaml.LiteralStyle
+		}
+	case yaml.MappingNode, yaml.SequenceNode:
+		// Empty collections stay inline, e.g. "parameters: {}".
+		if len(node.Content) > 0 {
+			node.Style = 0
+		}
+	}
+	for _, child := range node.Content {
+		blockStyle(child)
+	}
+}
@@ -0,0 +1,286 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// Keys of a configuration file that are merged away before decoding: include lists the files the
+// configuration builds on, and profiles the named overlays that can be selected when loading.
+const (
+	includeKey  = "include"
+	profilesKey = "profiles"
+)
+
+// decodeFunc decodes the content of a configuration file into a generic map.
+type decodeFunc func(data []byte) (map[string]interface{}, error)
+
+// loadLayers reads the configuration file with decode and returns it merged with its includes and the
+// selected profiles. The merge order, from lowest to highest precedence, is:
+//
+//  1. the included files, in the order they are listed, each merged with its own includes first
+//  2. the file itself
+//  3. the selected profiles, in the order they are selected
+//
+// Environment overrides are applied later, by resolveConfig. Included files are decoded by their extension,
+// and relative include paths are relative to the including file. Profiles may be defined in any of the files.
+func loadLayers(filePath string, decode decodeFunc, profiles []string) (map[string]interface{}, error) {
+	raw, err := loadIncludes(filePath, decode, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	return applyProfiles(raw, profiles)
+}
+
+// loadIncludes reads the configuration file and merges it over its includes. chain lists the files
+// including this one, to detect include cycles. When files is set, the paths of the files it reads are appended to it.
+func loadIncludes(filePath string, decode decodeFunc, chain []string, files *[]string) (map[string]interface{}, error) {
+	abs, err := filepath.Abs(filePath)
+	if err != nil {
+		return nil, err
+	}
+	for _, parent := range chain {
+		if parent == abs {
+			return nil, fmt.Errorf("include cycle: %s -> %s", strings.Join(chain, " -> "), abs)
+		}
+	}
+
+	if files != nil {
+		*files = append(*files, abs)
+	}
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("error reading file: %w", err)
+	}
+	raw, err := decode(data)
+	if err != nil {
+		return nil, err
+	}
+	if raw == nil {
+		raw = make(map[string]interface{})
+	}
+
+	var includes []string
+	switch v := raw[includeKey].(type) {
+	case nil:
+	case string:
+		includes = []string{v}
+	case []interface{}:
+		for _, include := range v {
+			path, ok := include.(string)
+			if !ok {
+				return nil, fmt.Errorf("%s in %s must list file paths", includeKey, filePath)
+			}
+			includes = append(includes, path)
+		}
+	default:
+		return nil, fmt.Errorf("%s in %s must be a file path or a list of file paths", includeKey, filePath)
+	}
+	delete(raw, includeKey)
+
+	merged := make(map[string]interface{})
+	for _, include := range includes {
+		path := include
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(filepath.Dir(filePath), path)
+		}
+		included, err := loadIncludes(path, decoderFor(path), append(chain, abs), files)
+		if err != nil {
+			return nil, fmt.Errorf("include %s: %w", include, err)
+		}
+		merged = merge(merged, included).(map[string]interface{})
+	}
+	return merge(merged, raw).(map[string]interface{}), nil
+}
+
+// configFiles returns the paths of the configuration file and of the files it includes, directly or not.
+// Files that can't be read or decoded are listed, but not their includes.
+func configFiles(filePath string) []string {
+	var files []string
+	_, _ = loadIncludes(filePath, decoderFor(filePath), nil, &files)
+	return files
+}
+
+// applyProfiles merges the selected profiles over the configuration, in order, after checking that every
+// defined profile only sets configuration entries. Selecting an undefined profile is an error.
+func applyProfiles(raw map[string]interface{}, selected []string) (map[string]interface{}, error) {
+	defined := make(map[string]interface{})
+	if value, exists := raw[profilesKey]; exists {
+		var ok bool
+		if defined, ok = value.(map[string]interface{}); !ok {
+			return nil, fmt.Errorf("%s must map profile names to configuration entries", profilesKey)
+		}
+	}
+	delete(raw, profilesKey)
+
+	known := configKeys()
+	for name, profile := range defined {
+		overlay, ok := profile.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("profile %q must be an object of configuration entries", name)
+		}
+		for key := range overlay {
+			if !known[key] {
+				return nil, fmt.Errorf("profile %q sets unknown configuration entry %q", name, key)
+			}
+		}
+	}
+
+	for _, name := range selected {
+		profile, exists := defined[name]
+		if !exists {
+			names := make([]string, 0, len(defined))
+			for n := range defined {
+				names = append(names, n)
+			}
+			sort.Strings(names)
+			return nil, fmt.Errorf("unknown profile %q, defined profiles: [%s]", name, strings.Join(names, ", "))
+		}
+		raw = merge(raw, profile).(map[string]interface{})
+	}
+	return raw, nil
+}
+
+// merge returns overlay merged over base. Objects merge key by key. Lists of objects identified by "name",
+// like pipelines and scenarios, or by "region", like gateways, merge entry by entry, and new entries are
+// appended. Any other value, including other lists, is replaced by the overlay's.
+func merge(base, overlay interface{}) interface{} {
+	switch o := overlay.(type) {
+	case map[string]interface{}:
+		b, ok := base.(map[string]interface{})
+		if !ok {
+			return o
+		}
+		merged := make(map[string]interface{}, len(b)+len(o))
+		for key, value := range b {
+			merged[key] = value
+		}
+		for key, value := range o {
+			if existing, exists := merged[key]; exists {
+				merged[key] = merge(existing, value)
+			} else {
+				merged[key] = value
+			}
+		}
+		return merged
+	case []interface{}:
+		b, ok := base.([]interface{})
+		if !ok {
+			return o
+		}
+		for _, key := range []string{"name", "region"} {
+			if keyedList(b, key) && keyedList(o, key) {
+				return mergeList(b, o, key)
+			}
+		}
+		return o
+	default:
+		return overlay
+	}
+}
+
+// keyedList reports whether every entry of the list is an object with a string value for key.
+func keyedList(list []interface{}, key string) bool {
+	for _, entry := range list {
+		object, ok := entry.(map[string]interface{})
+		if !ok {
+			return false
+		}
+		if _, ok := object[key].(string); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// mergeList merges the overlay entries over the base entries with the same key, keeping the base order
+// and appending the new entries.
+func mergeList(base, overlay []interface{}, key string) []interface{} {
+	merged := append([]interface{}(nil), base...)
+	index := make(map[string]int, len(base))
+	for i, entry := range base {
+		index[entry.(map[string]interface{})[key].(string)] = i
+	}
+	for _, entry := range overlay {
+		id := entry.(map[string]interface{})[key].(string)
+		if i, exists := index[id]; exists {
+			merged[i] = merge(merged[i], entry)
+			continue
+		}
+		index[id] = len(merged)
+		merged = append(merged, entry)
+	}
+	return merged
+}
+
+// configKeys returns the JSON names of the top-level configuration entries.
+func configKeys() map[string]bool {
+	keys := make(map[string]bool)
+	configType := reflect.TypeOf(Config{})
+	for i := 0; i < configType.NumField(); i++ {
+		if name := jsonName(configType.Field(i)); name != "" {
+			keys[name] = true
+		}
+	}
+	return keys
+}
+
+// decoderFor returns the decoder of the configuration file's format, picked by its extension like NewLoader.
+func decoderFor(filePath string) decodeFunc {
+	switch strings.ToLower(filepath.Ext(filePath)) {
+	case ".yaml", ".yml":
+		return decodeYAML
+	case ".toml":
+		return decodeTOML
+	default:
+		return decodeJSON
+	}
+}
+
+// decodeJSON decodes a JSON configuration, keeping numbers as written.
+func decodeJSON(data []byte) (map[string]interface{}, error) {
+	var raw map[string]interface{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&raw); err != nil {
+		return nil, fmt.Errorf("error unmarshalling JSON: %w", err)
+	}
+	return raw, nil
+}
+
+// decodeYAML decodes a YAML configuration.
+func decodeYAML(data []byte) (map[string]interface{}, error) {
+	var raw map[string]interface{}
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("error unmarshalling YAML: %w", err)
+	}
+	return normalize(raw)
+}
+
+// decodeTOML decodes a TOML configuration.
+func decodeTOML(data []byte) (map[string]interface{}, error) {
+	var raw map[string]interface{}
+	if err := toml.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("error unmarshalling TOML: %w", err)
+	}
+	return normalize(raw)
+}
+
+// normalize converts a decoded configuration to the types of a decoded JSON configuration, so that every
+// format merges the same way; e.g. TOML decodes arrays of tables as []map[string]interface{}.
+func normalize(raw map[string]interface{}) (map[string]interface{}, error) {
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return nil, fmt.Errorf("error converting configuration: %w", err)
+	}
+	return decodeJSON(data)
+}
@@ -0,0 +1,67 @@
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// NewLoader returns the Loader of the configuration file's format, picked by its extension:
+// YAMLConfigLoader for .yaml and .yml, TOMLConfigLoader for .toml and JSONConfigLoader otherwise.
+// The profiles are applied over the configuration, in order.
+func NewLoader(filePath string, profiles ...string) Loader {
+	switch strings.ToLower(filepath.Ext(filePath)) {
+	case ".yaml", ".yml":
+		return &YAMLConfigLoader{Profiles: profiles}
+	case ".toml":
+		return &TOMLConfigLoader{Profiles: profiles}
+	default:
+		return &JSONConfigLoader{Profiles: profiles}
+	}
+}
+
+// YAMLConfigLoader is an implementation of Loader that loads configuration data from a YAML file.
+// Keys are the JSON names of the configuration fields, and the file decodes to the same Config.
+// Profiles are the names of the profiles applied over the configuration, in order.
+type YAMLConfigLoader struct {
+	Profiles []string
+}
+
+// Load reads the configuration from the specified YAML file, merged with its includes and profiles.
+// It returns the loaded Config struct or an error if the file
+// cannot be read or parsed correctly.
+func (l *YAMLConfigLoader) Load(filePath string) (*Config, error) {
+	raw, err := loadLayers(filePath, decodeYAML, l.Profiles)
+	if err != nil {
+		return nil, fmt.Errorf("[YAMLConfigLoader::LoadConfig] %w", err)
+	}
+
+	config, err := decodeConfig(raw)
+	if err != nil {
+		return nil, fmt.Errorf("[YAMLConfigLoader::LoadConfig] %w", err)
+	}
+	return config, nil
+}
+
+// TOMLConfigLoader is an implementation of Loader that loads configuration data from a TOML file.
+// Keys are the JSON names of the configuration fields, and the file decodes to the same Config.
+// Profiles are the names of the profiles applied over the configuration, in order.
+type TOMLConfigLoader struct {
+	Profiles []string
+}
+
+// Load reads the configuration from the specified TOML file, merged with its includes and profiles.
+// It returns the loaded Config struct or an error if the file
+// cannot be read or parsed correctly.
+func (l *TOMLConfigLoader) Load(filePath string) (*Config, error) {
+	raw, err := loadLayers(filePath, decodeTOML, l.Profiles)
+	if err != nil {
+		return nil, fmt.Errorf("[TOMLConfigLoader::LoadConfig] %w", err)
+	}
+
+	config, err := decodeConfig(raw)
+	if err != nil {
+		return nil, fmt.Errorf("[TOMLConfigLoader::LoadConfig] %w", err)
+	}
+	return config, nil
+}
@@ -0,0 +1,95 @@
+package config
+
+import (
+	"context"
+	"crypto/sha256"
+	"os"
+	"reflect"
+	"time"
+)
+
+// Watch reloads the configuration file with the loader when its content or one of its includes changes,
+// checked every interval, and whenever a value is received on trigger, e.g. on SIGHUP. A triggered reload
+// also picks up changed environment variables and secret files. onReload receives the loaded configuration, or the error when the
+// file can't be loaded or is invalid. A zero interval disables the checks for changes. Watch returns when
+// the context is cancelled.
+func Watch(ctx context.Context, loader Loader, path string, interval time.Duration, trigger <-chan os.Signal, onReload func(*Config, error)) {
+	last := fingerprint(path)
+	var ticks <-chan time.Time
+	if interval > 0 {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		ticks = ticker.C
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-trigger:
+			last = fingerprint(path)
+		case <-ticks:
+			current := fingerprint(path)
+			if current == last {
+				continue
+			}
+			last = current
+		}
+		onReload(loader.Load(path))
+	}
+}
+
+// fingerprint returns the hash of the paths and content of the configuration file and its includes, see
+// configFiles. Files that can't be read only contribute their path. Files replaced by renaming, like
+// Kubernetes ConfigMap updates, are detected too.
+func fingerprint(path string) [sha256.Size]byte {
+	hash := sha256.New()
+	for _, file := range configFiles(path) {
+		hash.Write([]byte(file))
+		hash.Write([]byte{0})
+		if data, err := os.ReadFile(file); err == nil {
+			hash.Write(data)
+		}
+		hash.Write([]byte{0})
+	}
+	var sum [sha256.Size]byte
+	copy(sum[:], hash.Sum(nil))
+	return sum
+}
+
+// ChangesRequiringRestart returns the names of the settings that differ between the configurations but only
+// take effect on restart: the embedded web server listeners, path prefixes and webhook security, the admin
+// token, the log format, tracing, coordination and the gateway regions. Everything else is applied by a reload.
+func ChangesRequiringRestart(old, new *Config) []string {
+	type listener struct {
+		region  string
+		address string
+		prefix  string
+		webhook *Webhook
+	}
+	listeners := func(c *Config) []listener {
+		var out []listener
+		for _, gc := range c.GatewayConfigs() {
+			out = append(out, listener{region: gc.Region, address: gc.ListenAddress(), prefix: gc.PathPrefix, webhook: gc.Webhook})
+		}
+		return out
+	}
+
+	settings := []struct {
+		name     string
+		old, new interface{}
+	}{
+		{"region, gateways, listeners, pathPrefix or webhook", listeners(old), listeners(new)},
+		{"adminToken", old.AdminToken, new.AdminToken},
+		{"logFormat", old.LogFormat, new.LogFormat},
+		{"tracing", old.Tracing, new.Tracing},
+		{"coordination", old.Coordination, new.Coordination},
+	}
+	var changed []string
+	for _, s := range settings {
+		if !reflect.DeepEqual(s.old, s.new) {
+			changed = append(changed, s.name)
+		}
+	}
+	return changed
+}
@@ -0,0 +1,88 @@
+package config
+
+import (
+	"fmt"
+	"livepeer-job-tester/internal/openapi"
+	"sort"
+)
+
+// validateRequestSchemas checks the parameters, assets and content type of every request pipeline against
+// the request schema of its uri in the OpenAPI spec, so that a typo fails at load instead of failing every
+// orchestrator's job. Each scenario is checked merged over the pipeline's parameters, and each model parameter
+// override merged over every scenario, like jobs are sent; errors are only reported once, where they come from.
+// Stream pipelines are served by the gateway's live API, not the runner's, and are not checked.
+func (c *Config) validateRequestSchemas() []error {
+	spec, err := openapi.Load(c.OpenAPISpec)
+	if err != nil {
+		return []error{fmt.Errorf("openapiSpec: %w", err)}
+	}
+
+	var errs []error
+	for _, pipeline := range c.Pipelines {
+		if pipeline.IsStream() {
+			continue
+		}
+		contentType := requestContentType(pipeline.ContentType)
+		check := func(parameters map[string]interface{}, assets map[string]string) []error {
+			files := make([]string, 0, len(assets))
+			for field := range assets {
+				files = append(files, field)
+			}
+			return spec.Check(pipeline.Uri, contentType, parameters, files)
+		}
+
+		reported := make(map[string]bool)
+		for _, err := range check(pipeline.Parameters, pipeline.Assets) {
+			reported[err.Error()] = true
+			errs = append(errs, fmt.Errorf("pipeline %q: %w", pipeline.Name, err))
+		}
+		for _, scenario := range pipeline.ResolvedScenarios() {
+			if scenario.Name == "" {
+				continue
+			}
+			for _, err := range check(scenario.Parameters, scenario.Assets) {
+				if !reported[err.Error()] {
+					reported[scenario.Name+": "+err.Error()] = true
+					errs = append(errs, fmt.Errorf("pipeline %q scenario %q: %w", pipeline.Name, scenario.Name, err))
+				}
+			}
+		}
+
+		models := make([]string, 0, len(pipeline.ModelParameters))
+		for model := range pipeline.ModelParameters {
+			models = append(models, model)
+		}
+		sort.Strings(models)
+		for _, model := range models {
+			for _, scenario := range pipeline.ResolvedScenarios() {
+				parameters := make(map[string]interface{}, len(scenario.Parameters))
+				for k, v := range scenario.Parameters {
+					parameters[k] = v
+				}
+				for k, v := range pipeline.ModelParameters[model] {
+					parameters[k] = v
+				}
+				for _, err := range check(parameters, scenario.Assets) {
+					if reported[err.Error()] || reported[scenario.Name+": "+err.Error()] {
+						continue
+					}
+					if scenario.Name == "" {
+						errs = append(errs, fmt.Errorf("pipeline %q model_parameters %q: %w", pipeline.Name, model, err))
+					} else {
+						errs = append(errs, fmt.Errorf("pipeline %q scenario %q model_parameters %q: %w", pipeline.Name, scenario.Name, model, err))
+					}
+				}
+			}
+		}
+	}
+	return errs
+}
+
+// requestContentType returns the content type a pipeline's jobs are sent with:
+// JSON pipelines send application/json, all others multipart form data.
+func requestContentType(contentType string) string {
+	if contentType == "application/json" {
+		return contentType
+	}
+	return "multipart/form-data"
+}
@@ -0,0 +1,271 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"unicode"
+)
+
+// EnvPrefix prefixes the environment variables overriding top-level configuration fields.
+// A field's variable is its JSON name in upper snake case, e.g. JOBTESTER_METRICS_SECRET for metricsSecret.
+const EnvPrefix = "JOBTESTER_"
+
+// redactedValue replaces secrets in a redacted configuration.
+const redactedValue = "*****"
+
+// filePrefix marks a string value that is read from a file, e.g. "file:/run/secrets/metrics_secret".
+const filePrefix = "file:"
+
+// resolveConfig applies the environment overrides to the raw configuration, decoded from any file format,
+// and resolves the ${ENV_VAR} and file: references of its secrets. It returns the resolved JSON configuration.
+func resolveConfig(raw map[string]interface{}) ([]byte, error) {
+	if raw == nil {
+		raw = make(map[string]interface{})
+	}
+	if err := applyEnvOverrides(raw); err != nil {
+		return nil, err
+	}
+	resolved, err := resolveReferences(raw, reflect.TypeOf(Config{}), false, "")
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(resolved)
+}
+
+// applyEnvOverrides replaces the top-level fields of the raw configuration with the values of their
+// environment variables. String fields take the value as is, other fields parse it as JSON.
+func applyEnvOverrides(raw map[string]interface{}) error {
+	configType := reflect.TypeOf(Config{})
+	for i := 0; i < configType.NumField(); i++ {
+		field := configType.Field(i)
+		name := jsonName(field)
+		if name == "" {
+			continue
+		}
+		value, ok := os.LookupEnv(EnvPrefix + envName(name))
+		if !ok {
+			continue
+		}
+		if field.Type.Kind() == reflect.String {
+			raw[name] = value
+			continue
+		}
+		var parsed interface{}
+		decoder := json.NewDecoder(strings.NewReader(value))
+		decoder.UseNumber()
+		if err := decoder.Decode(&parsed); err != nil {
+			return fmt.Errorf("invalid JSON in environment variable %s%s: %w", EnvPrefix, envName(name), err)
+		}
+		raw[name] = parsed
+	}
+	return nil
+}
+
+// resolveReferences resolves the references in the secrets of the raw configuration value, decoded into
+// a value of type t: the strings of the fields tagged `secret:"true"`, and of their lists and maps.
+// Other strings, e.g. prompts and parameters, are kept as written. path locates the value in error messages.
+func resolveReferences(value interface{}, t reflect.Type, secret bool, path string) (interface{}, error) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			itemType, itemSecret := t, secret
+			switch t.Kind() {
+			case reflect.Struct:
+				field, ok := fieldByJSONName(t, key)
+				if !ok {
+					continue
+				}
+				itemType, itemSecret = field.Type, field.Tag.Get("secret") == "true"
+			case reflect.Map:
+				itemType = t.Elem()
+			default:
+				continue
+			}
+			resolved, err := resolveReferences(item, itemType, itemSecret, joinPath(path, key))
+			if err != nil {
+				return nil, err
+			}
+			v[key] = resolved
+		}
+		return v, nil
+	case []interface{}:
+		if t.Kind() != reflect.Slice {
+			return v, nil
+		}
+		for i, item := range v {
+			resolved, err := resolveReferences(item, t.Elem(), secret, fmt.Sprintf("%s[%d]", path, i))
+			if err != nil {
+				return nil, err
+			}
+			v[i] = resolved
+		}
+		return v, nil
+	case string:
+		if !secret || t.Kind() != reflect.String {
+			return v, nil
+		}
+		resolved, err := resolveString(v)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		return resolved, nil
+	default:
+		return value, nil
+	}
+}
+
+// fieldByJSONName returns the field of the struct type encoded under the JSON key, matched
+// case-insensitively like encoding/json does.
+func fieldByJSONName(t reflect.Type, key string) (reflect.StructField, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if name := jsonName(field); name != "" && strings.EqualFold(name, key) {
+			return field, true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+// resolveString resolves a configuration string. A "file:<path>" value is replaced with the file's
+// content, without trailing newlines. Otherwise every ${NAME} is replaced with the environment variable,
+// ${NAME:-default} falls back to the default when the variable is unset or empty, and $${ is a literal ${.
+func resolveString(value string) (string, error) {
+	if path, ok := strings.CutPrefix(value, filePrefix); ok {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("failed to read secret file: %w", err)
+		}
+		return strings.TrimRight(string(content), "\r\n"), nil
+	}
+
+	var out strings.Builder
+	for {
+		start := strings.Index(value, "${")
+		if start < 0 {
+			out.WriteString(value)
+			return out.String(), nil
+		}
+		if start > 0 && value[start-1] == '$' {
+			out.WriteString(value[:start-1] + "${")
+			value = value[start+2:]
+			continue
+		}
+		end := strings.Index(value[start:], "}")
+		if end < 0 {
+			return "", fmt.Errorf("unterminated reference in %q", value)
+		}
+		out.WriteString(value[:start])
+		reference := value[start+2 : start+end]
+		name, fallback, hasFallback := strings.Cut(reference, ":-")
+		env, ok := os.LookupEnv(name)
+		switch {
+		case hasFallback && env == "":
+			out.WriteString(fallback)
+		case !ok:
+			return "", fmt.Errorf("environment variable %s is not set", name)
+		default:
+			out.WriteString(env)
+		}
+		value = value[start+end+1:]
+	}
+}
+
+// Redacted returns a copy of the configuration with the fields tagged `secret:"true"` replaced by "*****",
+// so it can be logged or printed. Secret maps keep their keys.
+func (c *Config) Redacted() *Config {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return &Config{}
+	}
+	var redacted Config
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&redacted); err != nil {
+		return &Config{}
+	}
+	redact(reflect.ValueOf(&redacted).Elem())
+	return &redacted
+}
+
+// redact replaces the secrets of the value in place.
+func redact(v reflect.Value) {
+	switch v.Kind() {
+	case reflect.Pointer:
+		if !v.IsNil() {
+			redact(v.Elem())
+		}
+	case reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			redact(v.Index(i))
+		}
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			field := v.Type().Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			if field.Tag.Get("secret") != "true" {
+				redact(v.Field(i))
+				continue
+			}
+			redactSecret(v.Field(i))
+		}
+	}
+}
+
+// redactSecret replaces a secret string, or the values of a secret string map.
+func redactSecret(v reflect.Value) {
+	switch v.Kind() {
+	case reflect.String:
+		if v.String() != "" {
+			v.SetString(redactedValue)
+		}
+	case reflect.Map:
+		if v.Type().Elem().Kind() != reflect.String {
+			return
+		}
+		for _, key := range v.MapKeys() {
+			v.SetMapIndex(key, reflect.ValueOf(redactedValue).Convert(v.Type().Elem()))
+		}
+	}
+}
+
+// jsonName returns the JSON name of a struct field, or "" if it isn't encoded.
+func jsonName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if name == "-" || !field.IsExported() {
+		return ""
+	}
+	if name == "" {
+		return field.Name
+	}
+	return name
+}
+
+// envName converts a camel case JSON name to upper snake case, e.g. metricsApiEndpoint to METRICS_API_ENDPOINT.
+func envName(name string) string {
+	var out strings.Builder
+	runes := []rune(name)
+	for i, r := range runes {
+		if i > 0 && unicode.IsUpper(r) && (unicode.IsLower(runes[i-1]) || unicode.IsDigit(runes[i-1])) {
+			out.WriteByte('_')
+		}
+		out.WriteRune(unicode.ToUpper(r))
+	}
+	return out.String()
+}
+
+// joinPath appends a key to a configuration path.
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
@@ -0,0 +1,187 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Validate checks the configuration for mistakes that would otherwise only surface while testing,
+// e.g. missing or conflicting pipelines, invalid patterns and durations, or incomplete TLS and webhook settings.
+// It returns all the mistakes found, joined.
+func (c *Config) Validate() error {
+	var errs []error
+	owners := make(map[string]string)
+	for _, pipeline := range c.Pipelines {
+		if pipeline.Uri == "" {
+			errs = append(errs, fmt.Errorf("pipeline %q has no uri", pipeline.Name))
+		}
+		names := make(map[string]bool)
+		for _, scenario := range pipeline.Scenarios {
+			if names[scenario.Name] {
+				errs = append(errs, fmt.Errorf("pipeline %q has more than one scenario named %q", pipeline.Name, scenario.Name))
+			}
+			names[scenario.Name] = true
+		}
+		for key := range pipeline.ModelParameters {
+			if _, err := compileModelPattern(key); err != nil {
+				errs = append(errs, fmt.Errorf("pipeline %q has invalid model_parameters key %q: %w", pipeline.Name, key, err))
+			}
+		}
+		for _, scenario := range pipeline.ResolvedScenarios() {
+			if _, err := scenario.TimeoutDuration(); err != nil {
+				errs = append(errs, fmt.Errorf("pipeline %q: %w", pipeline.Name, err))
+			}
+			if v := scenario.Validator; v != nil {
+				switch v.Type {
+				case "", ValidatorStatus, ValidatorJSON, ValidatorMedia:
+				default:
+					errs = append(errs, fmt.Errorf("pipeline %q has unknown validator type %q", pipeline.Name, v.Type))
+				}
+			}
+		}
+		switch pipeline.Kind {
+		case "", PipelineKindRequest:
+		case PipelineKindStream:
+			if _, err := pipeline.StreamSettings(); err != nil {
+				errs = append(errs, err)
+			}
+		default:
+			errs = append(errs, fmt.Errorf("pipeline %q has unknown kind %q", pipeline.Name, pipeline.Kind))
+		}
+		for _, key := range pipeline.MatchKeys() {
+			if owner, exists := owners[key]; exists {
+				errs = append(errs, fmt.Errorf("pipelines %q and %q both match capability type %q", owner, pipeline.Name, key))
+				continue
+			}
+			owners[key] = pipeline.Name
+		}
+	}
+	if c.Tracing != nil && c.Tracing.Endpoint == "" {
+		errs = append(errs, errors.New("tracing has no endpoint"))
+	}
+	if c.TLS != nil {
+		destinations := []struct {
+			name string
+			tls  *TLS
+		}{{"gatewayCli", c.TLS.GatewayCli}, {"gatewayJob", c.TLS.GatewayJob}, {"metricsApi", c.TLS.MetricsApi}}
+		for _, d := range destinations {
+			if d.tls != nil && (d.tls.CertFile == "") != (d.tls.KeyFile == "") {
+				errs = append(errs, fmt.Errorf("tls %s needs both certFile and keyFile", d.name))
+			}
+		}
+	}
+	if len(c.Gateways) > 0 {
+		errs = append(errs, c.validateGateways()...)
+	}
+	if c.Webhook != nil {
+		errs = append(errs, c.Webhook.validate()...)
+	}
+	if c.Coordination != nil {
+		errs = append(errs, c.validateCoordination()...)
+	}
+	if c.MetricsBatch != nil {
+		if c.MetricsBatch.Endpoint == "" {
+			errs = append(errs, errors.New("metricsBatch has no endpoint"))
+		}
+		if c.MetricsBatch.MaxSize < 0 {
+			errs = append(errs, fmt.Errorf("metricsBatch maxSize must not be negative, got %d", c.MetricsBatch.MaxSize))
+		}
+		if _, err := c.MetricsBatch.WaitDuration(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if c.OpenAPISpec != "" {
+		errs = append(errs, c.validateRequestSchemas()...)
+	}
+	return errors.Join(errs...)
+}
+
+// validateCoordination checks that coordination has exactly one store, valid durations and,
+// when regions are listed, that they include the region of every gateway.
+func (c *Config) validateCoordination() []error {
+	var errs []error
+	coordination := c.Coordination
+	if (coordination.LockFile == "") == (coordination.CoordinatorURL == "") {
+		errs = append(errs, errors.New("coordination needs either a lockFile or a coordinatorUrl"))
+	}
+	if _, err := coordination.LeaseDuration(); err != nil {
+		errs = append(errs, err)
+	}
+	if _, err := coordination.WaitDuration(); err != nil {
+		errs = append(errs, err)
+	}
+	if len(coordination.Regions) == 0 {
+		return errs
+	}
+	listed := make(map[string]bool)
+	for _, region := range coordination.Regions {
+		if listed[region] {
+			errs = append(errs, fmt.Errorf("coordination region %q is listed twice", region))
+		}
+		listed[region] = true
+	}
+	for _, gc := range c.GatewayConfigs() {
+		if !listed[gc.Region] {
+			errs = append(errs, fmt.Errorf("coordination regions don't list region %q", gc.Region))
+		}
+	}
+	return errs
+}
+
+// validateGateways checks that every gateway has a unique region and its own webhook: no two gateways
+// may share both the listener and the path prefix. Gateways sharing a listener share its HTTPS settings,
+// so they can't configure different webhook certificates.
+func (c *Config) validateGateways() []error {
+	var errs []error
+	regions := make(map[string]bool)
+	webhooks := make(map[string]string)
+	certs := make(map[string]string)
+	for i, gc := range c.GatewayConfigs() {
+		gateway := c.Gateways[i]
+		switch {
+		case gateway.Region == "":
+			errs = append(errs, fmt.Errorf("gateway %d has no region", i))
+		case regions[gateway.Region]:
+			errs = append(errs, fmt.Errorf("gateway region %q is used twice", gateway.Region))
+		}
+		regions[gateway.Region] = true
+		if gc.BroadcasterJobEndpoint == "" || gc.BroadcasterCliEndpoint == "" {
+			errs = append(errs, fmt.Errorf("gateway %q needs broadcasterJobEndpoint and broadcasterCliEndpoint", gateway.Region))
+		}
+		if gateway.Webhook != nil {
+			errs = append(errs, gateway.Webhook.validate()...)
+		}
+		if gateway.PathPrefix != "" && (!strings.HasPrefix(gateway.PathPrefix, "/") || strings.HasSuffix(gateway.PathPrefix, "/")) {
+			errs = append(errs, fmt.Errorf("gateway %q pathPrefix must start and not end with /", gateway.Region))
+		}
+
+		webhook := gc.ListenAddress() + gc.PathPrefix + "/orchestrators"
+		if owner, exists := webhooks[webhook]; exists {
+			errs = append(errs, fmt.Errorf("gateways %q and %q both serve the webhook at %s", owner, gateway.Region, webhook))
+		}
+		webhooks[webhook] = gateway.Region
+
+		cert := ""
+		if gc.Webhook != nil {
+			cert = gc.Webhook.CertFile
+		}
+		if other, exists := certs[gc.ListenAddress()]; exists && other != cert {
+			errs = append(errs, fmt.Errorf("gateway %q shares listener %s with different webhook certificates", gateway.Region, gc.ListenAddress()))
+		}
+		certs[gc.ListenAddress()] = cert
+	}
+	return errs
+}
+
+// validate checks that the IP allowlist parses and that the certificate has a key.
+func (w *Webhook) validate() []error {
+	var errs []error
+	if _, err := w.AllowedNetworks(); err != nil {
+		errs = append(errs, err)
+	}
+	if (w.CertFile == "") != (w.KeyFile == "") {
+		errs = append(errs, errors.New("webhook needs both certFile and keyFile"))
+	}
+	return errs
+}
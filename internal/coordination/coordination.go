@@ -0,0 +1,225 @@
+package coordination
+
+import (
+	"context"
+	"fmt"
+	"livepeer-job-tester/internal/config"
+	"livepeer-job-tester/internal/services"
+	"log/slog"
+	"net/http"
+	"sort"
+	"time"
+)
+
+// Store is shared by the testers of several regions. It hands out leases on orchestrators, so only one
+// region tests an orchestrator at a time, and keeps the summary of each region's last round for comparison.
+type Store interface {
+	// Acquire takes the lease on key for holder, or extends it when holder already has it.
+	// It returns the current lease and whether holder has it.
+	Acquire(ctx context.Context, key, holder string, ttl time.Duration) (Lease, bool, error)
+	// Release gives up the lease on key if holder has it.
+	Release(ctx context.Context, key, holder string) error
+	// PutSummary replaces the round summary of the region.
+	PutSummary(ctx context.Context, summary RegionSummary) error
+	// Summaries returns the summary of the last round of every region, sorted by region.
+	Summaries(ctx context.Context) ([]RegionSummary, error)
+}
+
+// Lease gives holder exclusive use of key until ExpiresAt (Unix milliseconds).
+type Lease struct {
+	Key       string `json:"key"`
+	Holder    string `json:"holder"`
+	ExpiresAt int64  `json:"expires_at"`
+}
+
+// RegionSummary summarizes the last test round of a region, with the results of each orchestrator.
+type RegionSummary struct {
+	Region        string                                  `json:"region"`
+	RoundID       string                                  `json:"round_id"`
+	StartedAt     int64                                   `json:"started_at"`
+	FinishedAt    int64                                   `json:"finished_at"`
+	Metrics       services.MetricsSummary                 `json:"metrics"`
+	Orchestrators map[string]services.OrchestratorSummary `json:"orchestrators"`
+}
+
+// New creates the store configured by the coordination settings: a lock file shared by the testers,
+// or a coordinator reached with the HTTP client.
+func New(c *config.Coordination, client *http.Client) (Store, error) {
+	switch {
+	case c.LockFile != "":
+		return NewFileStore(c.LockFile), nil
+	case c.CoordinatorURL != "":
+		return NewHTTPStore(c.CoordinatorURL, c.Token, client), nil
+	default:
+		return nil, fmt.Errorf("[coordination::New] coordination needs a lockFile or a coordinatorUrl")
+	}
+}
+
+// Hold acquires the lease on key and renews it every third of the TTL, so it doesn't expire while the
+// holder is still busy, until release is called. Failed renewals are logged. When the lease is held
+// by someone else, acquired is false and release does nothing.
+func Hold(ctx context.Context, store Store, key, holder string, ttl time.Duration) (release func(), acquired bool, err error) {
+	if _, acquired, err = store.Acquire(ctx, key, holder, ttl); err != nil || !acquired {
+		return func() {}, acquired, err
+	}
+
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(ttl / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if lease, ok, err := store.Acquire(ctx, key, holder, ttl); err != nil {
+					slog.Warn("Failed to renew lease", "key", key, "err", err)
+				} else if !ok {
+					slog.Warn("Lease lost to another holder", "key", key, "holder", lease.Holder)
+				}
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+		<-stopped
+		// The lease is released even when the round was cancelled.
+		releaseCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 10*time.Second)
+		defer cancel()
+		if err := store.Release(releaseCtx, key, holder); err != nil {
+			slog.Warn("Failed to release lease", "key", key, "err", err)
+		}
+	}, true, nil
+}
+
+// Stagger rotates the items so that every region starts at a different point of the same list:
+// region i of n starts i/n of the way through. Items are returned unchanged when the region isn't listed.
+func Stagger[T any](items []T, regions []string, region string) []T {
+	index := -1
+	for i, r := range regions {
+		if r == region {
+			index = i
+			break
+		}
+	}
+	if index <= 0 || len(items) == 0 {
+		return items
+	}
+	offset := index * len(items) / len(regions)
+	return append(append([]T(nil), items[offset:]...), items[:offset]...)
+}
+
+// Comparison compares the last rounds of the regions: their overall results and, per orchestrator,
+// the results of every region that tested it.
+type Comparison struct {
+	Regions       []RegionResult           `json:"regions"`
+	Orchestrators []OrchestratorComparison `json:"orchestrators"`
+}
+
+// RegionResult is the overall result of a region's last round. MeanRoundTripTime is averaged over its jobs.
+type RegionResult struct {
+	Region            string  `json:"region"`
+	RoundID           string  `json:"round_id"`
+	FinishedAt        int64   `json:"finished_at"`
+	TotalJobs         int     `json:"total_jobs"`
+	Passed            int     `json:"passed"`
+	Failed            int     `json:"failed"`
+	TesterError       int     `json:"tester_error"`
+	MeanRoundTripTime float64 `json:"mean_round_trip_time"`
+}
+
+// OrchestratorComparison holds the results of an orchestrator keyed by region.
+type OrchestratorComparison struct {
+	Orchestrator string                                  `json:"orchestrator"`
+	Regions      map[string]services.OrchestratorSummary `json:"regions"`
+}
+
+// Compare builds the comparison of the region summaries. Orchestrators are sorted by address.
+func Compare(summaries []RegionSummary) Comparison {
+	comparison := Comparison{Regions: make([]RegionResult, 0, len(summaries)), Orchestrators: make([]OrchestratorComparison, 0)}
+	orchestrators := make(map[string]map[string]services.OrchestratorSummary)
+	for _, summary := range summaries {
+		result := RegionResult{
+			Region:      summary.Region,
+			RoundID:     summary.RoundID,
+			FinishedAt:  summary.FinishedAt,
+			TotalJobs:   summary.Metrics.TotalJobs,
+			Passed:      summary.Metrics.TotalJobsPassed,
+			Failed:      summary.Metrics.TotalJobsFailed,
+			TesterError: summary.Metrics.TotalJobsTesterError,
+		}
+		jobs := 0
+		for address, orch := range summary.Orchestrators {
+			result.MeanRoundTripTime += orch.MeanRoundTripTime * float64(orch.Total)
+			jobs += orch.Total
+			if orchestrators[address] == nil {
+				orchestrators[address] = make(map[string]services.OrchestratorSummary)
+			}
+			orchestrators[address][summary.Region] = orch
+		}
+		if jobs > 0 {
+			result.MeanRoundTripTime /= float64(jobs)
+		}
+		comparison.Regions = append(comparison.Regions, result)
+	}
+
+	for address, regions := range orchestrators {
+		comparison.Orchestrators = append(comparison.Orchestrators, OrchestratorComparison{Orchestrator: address, Regions: regions})
+	}
+	sort.Slice(comparison.Orchestrators, func(i, j int) bool {
+		return comparison.Orchestrators[i].Orchestrator < comparison.Orchestrators[j].Orchestrator
+	})
+	return comparison
+}
+
+// state is the content of a store: the leases by key and the summaries by region.
+type state struct {
+	Leases    map[string]Lease         `json:"leases"`
+	Summaries map[string]RegionSummary `json:"summaries"`
+}
+
+// newState returns an empty state.
+func newState() *state {
+	return &state{Leases: make(map[string]Lease), Summaries: make(map[string]RegionSummary)}
+}
+
+// acquire takes or extends the lease on key for holder unless someone else has an unexpired lease.
+func (s *state) acquire(key, holder string, ttl time.Duration, now time.Time) (Lease, bool) {
+	if lease, exists := s.Leases[key]; exists && lease.Holder != holder && lease.ExpiresAt > now.UnixMilli() {
+		return lease, false
+	}
+	lease := Lease{Key: key, Holder: holder, ExpiresAt: now.Add(ttl).UnixMilli()}
+	s.Leases[key] = lease
+	return lease, true
+}
+
+// release removes the lease on key if holder has it. It reports whether the state changed.
+func (s *state) release(key, holder string) bool {
+	if lease, exists := s.Leases[key]; exists && lease.Holder == holder {
+		delete(s.Leases, key)
+		return true
+	}
+	return false
+}
+
+// expire removes the leases that expired before now.
+func (s *state) expire(now time.Time) {
+	for key, lease := range s.Leases {
+		if lease.ExpiresAt <= now.UnixMilli() {
+			delete(s.Leases, key)
+		}
+	}
+}
+
+// summaries returns the region summaries sorted by region.
+func (s *state) summaries() []RegionSummary {
+	summaries := make([]RegionSummary, 0, len(s.Summaries))
+	for _, summary := range s.Summaries {
+		summaries = append(summaries, summary)
+	}
+	sort.Slice(summaries, func(i, j int) bool { return summaries[i].Region < summaries[j].Region })
+	return summaries
+}
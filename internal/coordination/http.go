@@ -0,0 +1,182 @@
+package coordination
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// leaseRequest is the body of the coordinator's lease requests. TTL is in milliseconds.
+type leaseRequest struct {
+	Key    string `json:"key"`
+	Holder string `json:"holder"`
+	TTL    int64  `json:"ttl,omitempty"`
+}
+
+// leaseResponse is the body of the coordinator's response to a lease request.
+type leaseResponse struct {
+	Lease    Lease `json:"lease"`
+	Acquired bool  `json:"acquired"`
+}
+
+// HTTPStore is a Store kept by a coordinator, see Handler.
+type HTTPStore struct {
+	baseURL string
+	token   string
+	client  *http.Client
+}
+
+// NewHTTPStore returns a store kept by the coordinator at baseURL, authenticated with the bearer token, if any.
+func NewHTTPStore(baseURL, token string, client *http.Client) *HTTPStore {
+	return &HTTPStore{baseURL: strings.TrimSuffix(baseURL, "/"), token: token, client: client}
+}
+
+// Acquire implements Store.
+func (h *HTTPStore) Acquire(ctx context.Context, key, holder string, ttl time.Duration) (Lease, bool, error) {
+	var res leaseResponse
+	err := h.do(ctx, "POST", "/leases", leaseRequest{Key: key, Holder: holder, TTL: ttl.Milliseconds()}, &res)
+	return res.Lease, res.Acquired, err
+}
+
+// Release implements Store.
+func (h *HTTPStore) Release(ctx context.Context, key, holder string) error {
+	return h.do(ctx, "DELETE", "/leases", leaseRequest{Key: key, Holder: holder}, nil)
+}
+
+// PutSummary implements Store.
+func (h *HTTPStore) PutSummary(ctx context.Context, summary RegionSummary) error {
+	return h.do(ctx, "POST", "/summaries", summary, nil)
+}
+
+// Summaries implements Store.
+func (h *HTTPStore) Summaries(ctx context.Context) ([]RegionSummary, error) {
+	var summaries []RegionSummary
+	err := h.do(ctx, "GET", "/summaries", nil, &summaries)
+	return summaries, err
+}
+
+// do sends a request with the JSON body, if any, to the coordinator and decodes the JSON response into out, if not nil.
+func (h *HTTPStore) do(ctx context.Context, method, path string, body, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("[HTTPStore::do] error marshalling request: %w", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, h.baseURL+path, reader)
+	if err != nil {
+		return fmt.Errorf("[HTTPStore::do] error creating request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if h.token != "" {
+		req.Header.Set("Authorization", "Bearer "+h.token)
+	}
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("[HTTPStore::do] error sending request to coordinator: %w", err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("[HTTPStore::do] error reading coordinator response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("[HTTPStore::do] coordinator returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("[HTTPStore::do] error unmarshalling coordinator response: %w", err)
+	}
+	return nil
+}
+
+// Handler serves the store to the testers of every region, as used by HTTPStore:
+//
+//	POST   /leases     acquire or renew {"key", "holder", "ttl"} (milliseconds)
+//	DELETE /leases     release {"key", "holder"}
+//	GET    /summaries  the last round summary of every region
+//	POST   /summaries  replace the round summary of a region
+//
+// When token is set, every request must carry it as a bearer token.
+func Handler(store Store, token string) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/leases", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" && r.Method != "DELETE" {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		var req leaseRequest
+		if err := json.NewDecoder(io.LimitReader(r.Body, 1<<20)).Decode(&req); err != nil || req.Key == "" || req.Holder == "" {
+			http.Error(w, "key and holder are required", http.StatusBadRequest)
+			return
+		}
+		if r.Method == "DELETE" {
+			respond(w, struct{}{}, store.Release(r.Context(), req.Key, req.Holder))
+			return
+		}
+		if req.TTL <= 0 {
+			http.Error(w, "ttl must be positive", http.StatusBadRequest)
+			return
+		}
+		lease, acquired, err := store.Acquire(r.Context(), req.Key, req.Holder, time.Duration(req.TTL)*time.Millisecond)
+		respond(w, leaseResponse{Lease: lease, Acquired: acquired}, err)
+	})
+	mux.HandleFunc("/summaries", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "GET":
+			summaries, err := store.Summaries(r.Context())
+			respond(w, summaries, err)
+		case "POST":
+			var summary RegionSummary
+			if err := json.NewDecoder(io.LimitReader(r.Body, 8<<20)).Decode(&summary); err != nil || summary.Region == "" {
+				http.Error(w, "a summary with a region is required", http.StatusBadRequest)
+				return
+			}
+			respond(w, struct{}{}, store.PutSummary(r.Context(), summary))
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	})
+
+	if token == "" {
+		return mux
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		actual, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(actual), []byte(token)) != 1 {
+			slog.Warn("Rejected coordinator request", "remote_addr", r.RemoteAddr, "path", r.URL.Path, "reason", "invalid token")
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		mux.ServeHTTP(w, r)
+	})
+}
+
+// respond writes v as a JSON response, or the error with status 500.
+func respond(w http.ResponseWriter, v interface{}, err error) {
+	if err != nil {
+		slog.Error("Coordinator store error", "err", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
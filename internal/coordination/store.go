@@ -0,0 +1,165 @@
+package coordination
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// MemoryStore is a Store held in memory, used by the coordinator.
+// A mutex is used to safely handle concurrent updates.
+type MemoryStore struct {
+	lock  sync.Mutex
+	state *state
+}
+
+// NewMemoryStore returns an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{state: newState()}
+}
+
+// Acquire implements Store.
+func (m *MemoryStore) Acquire(ctx context.Context, key, holder string, ttl time.Duration) (Lease, bool, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	now := time.Now()
+	m.state.expire(now)
+	lease, acquired := m.state.acquire(key, holder, ttl, now)
+	return lease, acquired, nil
+}
+
+// Release implements Store.
+func (m *MemoryStore) Release(ctx context.Context, key, holder string) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.state.release(key, holder)
+	return nil
+}
+
+// PutSummary implements Store.
+func (m *MemoryStore) PutSummary(ctx context.Context, summary RegionSummary) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.state.Summaries[summary.Region] = summary
+	return nil
+}
+
+// Summaries implements Store.
+func (m *MemoryStore) Summaries(ctx context.Context) ([]RegionSummary, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return m.state.summaries(), nil
+}
+
+// FileStore is a Store kept in a JSON file shared by the testers, e.g. on a shared volume.
+// Every update holds an exclusive flock on "<path>.lock", so testers on the same host, or on a
+// file system with working flock, never see a partial update.
+type FileStore struct {
+	path string
+}
+
+// NewFileStore returns a FileStore kept in the file at path. The file is created on the first update.
+func NewFileStore(path string) *FileStore {
+	return &FileStore{path: path}
+}
+
+// Acquire implements Store.
+func (f *FileStore) Acquire(ctx context.Context, key, holder string, ttl time.Duration) (Lease, bool, error) {
+	var lease Lease
+	var acquired bool
+	err := f.update(func(s *state) bool {
+		now := time.Now()
+		s.expire(now)
+		lease, acquired = s.acquire(key, holder, ttl, now)
+		return acquired
+	})
+	return lease, acquired, err
+}
+
+// Release implements Store.
+func (f *FileStore) Release(ctx context.Context, key, holder string) error {
+	return f.update(func(s *state) bool {
+		return s.release(key, holder)
+	})
+}
+
+// PutSummary implements Store.
+func (f *FileStore) PutSummary(ctx context.Context, summary RegionSummary) error {
+	return f.update(func(s *state) bool {
+		s.Summaries[summary.Region] = summary
+		return true
+	})
+}
+
+// Summaries implements Store.
+func (f *FileStore) Summaries(ctx context.Context) ([]RegionSummary, error) {
+	var summaries []RegionSummary
+	err := f.update(func(s *state) bool {
+		summaries = s.summaries()
+		return false
+	})
+	return summaries, err
+}
+
+// update runs fn on the state of the file while holding the lock, and writes the state back when fn
+// reports a change. The file is replaced atomically, so readers never see a partial write.
+func (f *FileStore) update(fn func(*state) bool) error {
+	lockFile, err := os.OpenFile(f.path+".lock", os.O_CREATE|os.O_RDWR, 0o644)
+	if err != nil {
+		return fmt.Errorf("[FileStore::update] error opening lock file: %w", err)
+	}
+	defer lockFile.Close()
+	if err := syscall.Flock(int(lockFile.Fd()), syscall.LOCK_EX); err != nil {
+		return fmt.Errorf("[FileStore::update] error locking %s: %w", lockFile.Name(), err)
+	}
+	defer syscall.Flock(int(lockFile.Fd()), syscall.LOCK_UN)
+
+	s := newState()
+	data, err := os.ReadFile(f.path)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+	case err != nil:
+		return fmt.Errorf("[FileStore::update] error reading store: %w", err)
+	default:
+		if err := json.Unmarshal(data, s); err != nil {
+			return fmt.Errorf("[FileStore::update] error unmarshalling store: %w", err)
+		}
+		if s.Leases == nil {
+			s.Leases = make(map[string]Lease)
+		}
+		if s.Summaries == nil {
+			s.Summaries = make(map[string]RegionSummary)
+		}
+	}
+
+	if !fn(s) {
+		return nil
+	}
+	data, err = json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("[FileStore::update] error marshalling store: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(f.path), filepath.Base(f.path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("[FileStore::update] error writing store: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("[FileStore::update] error writing store: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("[FileStore::update] error writing store: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), f.path); err != nil {
+		return fmt.Errorf("[FileStore::update] error replacing store: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,134 @@
+package httpclient
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"livepeer-job-tester/internal/config"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+)
+
+// New creates the HTTP client for outbound calls. Requests to the gateway CLI endpoint, the gateway
+// job endpoint and the metrics API use the TLS settings of their destination, matched by host and port.
+// All other requests, e.g. to media URLs, verify server certificates against the system roots.
+func New(cfg *config.Config, timeout time.Duration) (*http.Client, error) {
+	settings := cfg.TLS
+	if settings == nil {
+		settings = &config.TLSSettings{}
+	}
+	return NewForDestinations(timeout,
+		Destination{"gatewayJob", cfg.BroadcasterJobEndpoint, settings.GatewayJob},
+		Destination{"gatewayCli", cfg.BroadcasterCliEndpoint, settings.GatewayCli},
+		Destination{"metricsApi", cfg.MetricsApiEndpoint, settings.MetricsApi},
+	)
+}
+
+// Destination is an endpoint called with its own TLS settings. Name identifies it in errors and logs.
+type Destination struct {
+	Name     string
+	Endpoint string
+	TLS      *config.TLS
+}
+
+// NewForDestinations creates an HTTP client sending the requests to each destination, matched by host and port,
+// with its TLS settings. Destinations without TLS settings, and all other hosts, use the system roots.
+func NewForDestinations(timeout time.Duration, destinations ...Destination) (*http.Client, error) {
+	transport := &destinationTransport{
+		routes:   make(map[string]http.RoundTripper),
+		fallback: newTransport(&tls.Config{}),
+	}
+	for _, d := range destinations {
+		if d.TLS == nil || d.Endpoint == "" {
+			continue
+		}
+		endpoint, err := url.Parse(d.Endpoint)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s endpoint: %w", d.Name, err)
+		}
+		host := hostPort(endpoint)
+		if _, exists := transport.routes[host]; exists {
+			slog.Warn("TLS settings ignored, another destination uses the same host", "destination", d.Name, "host", host)
+			continue
+		}
+		tlsConfig, err := NewTLSConfig(d.TLS)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s TLS settings: %w", d.Name, err)
+		}
+		if d.TLS.InsecureSkipVerify {
+			slog.Warn("TLS certificate verification is disabled, traffic can be intercepted", "destination", d.Name, "host", host)
+		}
+		transport.routes[host] = newTransport(tlsConfig)
+	}
+
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+	}, nil
+}
+
+// NewTLSConfig builds the TLS configuration of a destination: the system roots plus the custom CA bundle,
+// the client certificate for mutual TLS and, when opted in, no server certificate verification.
+func NewTLSConfig(t *config.TLS) (*tls.Config, error) {
+	tlsConfig := &tls.Config{InsecureSkipVerify: t.InsecureSkipVerify}
+
+	if t.CAFile != "" {
+		roots, err := x509.SystemCertPool()
+		if err != nil {
+			roots = x509.NewCertPool()
+		}
+		pem, err := os.ReadFile(t.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA file: %w", err)
+		}
+		if !roots.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in CA file %s", t.CAFile)
+		}
+		tlsConfig.RootCAs = roots
+	}
+
+	if t.CertFile != "" || t.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(t.CertFile, t.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	return tlsConfig, nil
+}
+
+// destinationTransport sends each request with the transport of its destination host.
+type destinationTransport struct {
+	routes   map[string]http.RoundTripper // Transports keyed by host:port.
+	fallback http.RoundTripper            // Transport of hosts without TLS settings.
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *destinationTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if transport, ok := t.routes[hostPort(req.URL)]; ok {
+		return transport.RoundTrip(req)
+	}
+	return t.fallback.RoundTrip(req)
+}
+
+// newTransport returns a transport with the default settings and the TLS configuration.
+func newTransport(tlsConfig *tls.Config) *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	return transport
+}
+
+// hostPort returns the host and port of the URL, with the scheme's default port when none is given.
+func hostPort(u *url.URL) string {
+	port := u.Port()
+	if port == "" {
+		port = "80"
+		if u.Scheme == "https" {
+			port = "443"
+		}
+	}
+	return u.Hostname() + ":" + port
+}
@@ -0,0 +1,153 @@
+package leaderboard
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+)
+
+// Paths of the Leaderboard API endpoints served by Handler.
+const (
+	PostStatsPath       = "/api/post_stats"
+	PostStatsBatchPath  = "/api/post_stats_batch"
+	RawStatsPath        = "/api/raw_stats"
+	AggregatedStatsPath = "/api/aggregated_stats"
+)
+
+// maxBodySize bounds the body of a post; stats records hold the response payloads.
+const maxBodySize = 32 << 20
+
+// Handler returns the HTTP handler of a Leaderboard API compatible server on top of the store:
+//
+//	POST /api/post_stats         stores a stats record
+//	POST /api/post_stats_batch   stores a JSON array of stats records, all or none
+//	GET  /api/raw_stats          the matching records keyed by region, oldest first
+//	GET  /api/aggregated_stats   the matching records aggregated per orchestrator and region, see Aggregate
+//
+// Posts must be signed with the secret, see Sign, and are rejected with 403 otherwise. Queries select records
+// with the orchestrator, region, pipeline, model, since and until parameters, see Query.
+func Handler(store Store, secret string) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc(PostStatsPath, func(w http.ResponseWriter, r *http.Request) {
+		body, ok := readSigned(w, r, secret)
+		if !ok {
+			return
+		}
+		var record Record
+		if err := json.Unmarshal(body, &record); err != nil {
+			http.Error(w, "invalid stats record: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := validate(record); err != nil {
+			http.Error(w, "invalid stats record: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		respond(w, struct{}{}, store.Add(r.Context(), []Record{record}))
+	})
+	mux.HandleFunc(PostStatsBatchPath, func(w http.ResponseWriter, r *http.Request) {
+		body, ok := readSigned(w, r, secret)
+		if !ok {
+			return
+		}
+		var records []Record
+		if err := json.Unmarshal(body, &records); err != nil {
+			http.Error(w, "invalid stats batch: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		for i, record := range records {
+			if err := validate(record); err != nil {
+				http.Error(w, fmt.Sprintf("invalid stats record %d: %v", i, err), http.StatusBadRequest)
+				return
+			}
+		}
+		respond(w, struct{}{}, store.Add(r.Context(), records))
+	})
+	mux.HandleFunc(RawStatsPath, func(w http.ResponseWriter, r *http.Request) {
+		query, ok := parseQuery(w, r)
+		if !ok {
+			return
+		}
+		records, err := store.Stats(r.Context(), query)
+		respond(w, byRegion(records), err)
+	})
+	mux.HandleFunc(AggregatedStatsPath, func(w http.ResponseWriter, r *http.Request) {
+		query, ok := parseQuery(w, r)
+		if !ok {
+			return
+		}
+		records, err := store.Stats(r.Context(), query)
+		respond(w, Aggregate(records), err)
+	})
+	return mux
+}
+
+// readSigned reads the body of a POST request and checks its signature, rejecting the request otherwise.
+// Bodies larger than maxBodySize are rejected with 413.
+func readSigned(w http.ResponseWriter, r *http.Request, secret string) ([]byte, bool) {
+	if r.Method != "POST" {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return nil, false
+	}
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBodySize))
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		http.Error(w, fmt.Sprintf("body larger than %d bytes", tooLarge.Limit), http.StatusRequestEntityTooLarge)
+		return nil, false
+	}
+	if err != nil {
+		http.Error(w, "error reading body", http.StatusBadRequest)
+		return nil, false
+	}
+	if !Verify(secret, body, r.Header.Get("Authorization")) {
+		slog.Warn("Rejected stats post", "remote_addr", r.RemoteAddr, "path", r.URL.Path, "reason", "invalid signature")
+		w.WriteHeader(http.StatusForbidden)
+		return nil, false
+	}
+	return body, true
+}
+
+// parseQuery parses the query parameters of a GET request, rejecting the request when they are invalid.
+func parseQuery(w http.ResponseWriter, r *http.Request) (Query, bool) {
+	if r.Method != "GET" {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return Query{}, false
+	}
+	values := r.URL.Query()
+	query := Query{
+		Orchestrator: values.Get("orchestrator"),
+		Region:       values.Get("region"),
+		Pipeline:     values.Get("pipeline"),
+		Model:        values.Get("model"),
+	}
+	for name, bound := range map[string]*int64{"since": &query.Since, "until": &query.Until} {
+		if value := values.Get(name); value != "" {
+			parsed, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				http.Error(w, fmt.Sprintf("invalid %s: %q, expected a unix timestamp", name, value), http.StatusBadRequest)
+				return Query{}, false
+			}
+			*bound = parsed
+		}
+	}
+	return query, true
+}
+
+// respond writes v as a JSON response, or the error with status 500.
+func respond(w http.ResponseWriter, v interface{}, err error) {
+	if err != nil {
+		slog.Error("Leaderboard store error", "err", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
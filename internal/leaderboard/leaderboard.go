@@ -0,0 +1,188 @@
+package leaderboard
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"livepeer-job-tester/internal/types"
+	"math"
+	"sort"
+	"strings"
+)
+
+// Store keeps the stats records posted by the testers.
+type Store interface {
+	// Add stores the records.
+	Add(ctx context.Context, records []Record) error
+	// Stats returns the records matching the query, oldest first.
+	Stats(ctx context.Context, query Query) ([]Record, error)
+}
+
+// Record is a posted stats record. It is stored and served as posted, so fields unknown to types.Stats,
+// e.g. from older testers or data-transfer, are kept; the decoded Stats are used to validate and query it.
+type Record struct {
+	types.Stats
+	raw json.RawMessage
+}
+
+// UnmarshalJSON keeps the posted JSON, compacted to a single line, and decodes the stats from it.
+func (r *Record) UnmarshalJSON(data []byte) error {
+	if err := json.Unmarshal(data, &r.Stats); err != nil {
+		return err
+	}
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, data); err != nil {
+		return err
+	}
+	r.raw = compact.Bytes()
+	return nil
+}
+
+// MarshalJSON returns the posted JSON, or the stats of a record built in code.
+func (r Record) MarshalJSON() ([]byte, error) {
+	if r.raw != nil {
+		return r.raw, nil
+	}
+	return json.Marshal(r.Stats)
+}
+
+// Query selects stats records. Empty fields match every record, and the orchestrator address is
+// compared case-insensitively. Since and Until bound the record timestamps, in unix seconds, when set.
+type Query struct {
+	Orchestrator string
+	Region       string
+	Pipeline     string
+	Model        string
+	Since        int64
+	Until        int64
+}
+
+// Matches reports whether the record is selected by the query.
+func (q Query) Matches(record Record) bool {
+	return (q.Orchestrator == "" || strings.EqualFold(q.Orchestrator, record.Orchestrator)) &&
+		(q.Region == "" || q.Region == record.Region) &&
+		(q.Pipeline == "" || q.Pipeline == record.Pipeline) &&
+		(q.Model == "" || q.Model == record.Model) &&
+		(q.Since == 0 || record.Timestamp >= q.Since) &&
+		(q.Until == 0 || record.Timestamp <= q.Until)
+}
+
+// Sign returns the signature of a posted body: the hex encoded HMAC-SHA256 of the body with the secret,
+// sent by the testers in the Authorization header.
+func Sign(secret string, body []byte) string {
+	hash := hmac.New(sha256.New, []byte(secret))
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// Verify reports whether the signature is the signature of the body with the secret.
+func Verify(secret string, body []byte, signature string) bool {
+	decoded, err := hex.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+	expected, _ := hex.DecodeString(Sign(secret, body))
+	return hmac.Equal(decoded, expected)
+}
+
+// validate checks that a posted record can be stored and queried.
+func validate(record Record) error {
+	switch {
+	case record.Orchestrator == "":
+		return errors.New("orchestrator is required")
+	case record.Region == "":
+		return errors.New("region is required")
+	case record.SuccessRate < 0 || record.SuccessRate > 1 || math.IsNaN(record.SuccessRate):
+		return errors.New("success_rate must be between 0 and 1")
+	case record.Timestamp <= 0:
+		return errors.New("timestamp is required")
+	}
+	return nil
+}
+
+// AggregatedStats summarizes the records of an orchestrator in a region.
+// SuccessRate is the mean success rate of the jobs and RoundTripTime the mean round-trip time of the jobs with
+// a successful sample. RoundTripScore compares it with the fastest orchestrator of the region: 1 for the fastest,
+// 0.5 for twice as slow. Score is SuccessRate multiplied by RoundTripScore.
+type AggregatedStats struct {
+	SuccessRate    float64 `json:"success_rate"`
+	RoundTripTime  float64 `json:"round_trip_time"`
+	RoundTripScore float64 `json:"round_trip_score"`
+	Score          float64 `json:"score"`
+	Jobs           int     `json:"jobs"`
+}
+
+// Aggregate summarizes the records per orchestrator and region, keyed by orchestrator address and region.
+// Records of several pipelines and models are aggregated together, so queries usually select one of each.
+func Aggregate(records []Record) map[string]map[string]AggregatedStats {
+	type totals struct {
+		successRate   float64
+		roundTripTime float64
+		succeeded     int
+		jobs          int
+	}
+	byOrchestrator := make(map[string]map[string]*totals)
+	for _, record := range records {
+		orchestrator := strings.ToLower(record.Orchestrator)
+		regions, exists := byOrchestrator[orchestrator]
+		if !exists {
+			regions = make(map[string]*totals)
+			byOrchestrator[orchestrator] = regions
+		}
+		t, exists := regions[record.Region]
+		if !exists {
+			t = &totals{}
+			regions[record.Region] = t
+		}
+		t.jobs++
+		t.successRate += record.SuccessRate
+		if record.SuccessRate > 0 {
+			t.succeeded++
+			t.roundTripTime += record.RoundTripTime
+		}
+	}
+
+	aggregated := make(map[string]map[string]AggregatedStats, len(byOrchestrator))
+	fastest := make(map[string]float64)
+	for orchestrator, regions := range byOrchestrator {
+		aggregated[orchestrator] = make(map[string]AggregatedStats, len(regions))
+		for region, t := range regions {
+			stats := AggregatedStats{SuccessRate: t.successRate / float64(t.jobs), Jobs: t.jobs}
+			if t.succeeded > 0 {
+				stats.RoundTripTime = t.roundTripTime / float64(t.succeeded)
+				if best, exists := fastest[region]; stats.RoundTripTime > 0 && (!exists || stats.RoundTripTime < best) {
+					fastest[region] = stats.RoundTripTime
+				}
+			}
+			aggregated[orchestrator][region] = stats
+		}
+	}
+	for _, regions := range aggregated {
+		for region, stats := range regions {
+			if stats.RoundTripTime > 0 {
+				stats.RoundTripScore = fastest[region] / stats.RoundTripTime
+			}
+			stats.Score = stats.SuccessRate * stats.RoundTripScore
+			regions[region] = stats
+		}
+	}
+	return aggregated
+}
+
+// byRegion groups the records by region, keeping their order.
+func byRegion(records []Record) map[string][]Record {
+	regions := make(map[string][]Record)
+	for _, record := range records {
+		regions[record.Region] = append(regions[record.Region], record)
+	}
+	return regions
+}
+
+// sortByTimestamp sorts the records oldest first, keeping the order of records with the same timestamp.
+func sortByTimestamp(records []Record) {
+	sort.SliceStable(records, func(i, j int) bool { return records[i].Timestamp < records[j].Timestamp })
+}
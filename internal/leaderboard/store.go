@@ -0,0 +1,117 @@
+package leaderboard
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"sync"
+)
+
+// MemoryStore is a Store held in memory, e.g. for tests or a throwaway deployment.
+// A mutex is used to safely handle concurrent updates.
+type MemoryStore struct {
+	lock    sync.RWMutex
+	records []Record
+}
+
+// NewMemoryStore returns an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{}
+}
+
+// Add implements Store.
+func (m *MemoryStore) Add(ctx context.Context, records []Record) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.records = append(m.records, records...)
+	return nil
+}
+
+// Stats implements Store.
+func (m *MemoryStore) Stats(ctx context.Context, query Query) ([]Record, error) {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	var matched []Record
+	for _, record := range m.records {
+		if query.Matches(record) {
+			matched = append(matched, record)
+		}
+	}
+	sortByTimestamp(matched)
+	return matched, nil
+}
+
+// FileStore is a Store kept in a JSON Lines file, one record per line, so the stats survive a restart.
+// The records are loaded when the store is opened and queried in memory; new records are appended to the file.
+type FileStore struct {
+	MemoryStore
+	path string
+	file *os.File
+}
+
+// OpenFileStore opens the FileStore kept in the file at path, creating the file if needed.
+// The caller should call Close when finished.
+func OpenFileStore(path string) (*FileStore, error) {
+	store := &FileStore{path: path}
+	data, err := os.Open(path)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+	case err != nil:
+		return nil, fmt.Errorf("[OpenFileStore] error opening store: %w", err)
+	default:
+		defer data.Close()
+		scanner := bufio.NewScanner(data)
+		scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+		for line := 1; scanner.Scan(); line++ {
+			if len(scanner.Bytes()) == 0 {
+				continue
+			}
+			var record Record
+			if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+				return nil, fmt.Errorf("[OpenFileStore] error unmarshalling %s line %d: %w", path, line, err)
+			}
+			store.records = append(store.records, record)
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, fmt.Errorf("[OpenFileStore] error reading store: %w", err)
+		}
+	}
+
+	store.file, err = os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("[OpenFileStore] error opening store: %w", err)
+	}
+	return store, nil
+}
+
+// Add implements Store. The records are written to the file before they can be queried.
+func (f *FileStore) Add(ctx context.Context, records []Record) error {
+	var lines []byte
+	for _, record := range records {
+		line, err := json.Marshal(record)
+		if err != nil {
+			return fmt.Errorf("[FileStore::Add] error marshalling record: %w", err)
+		}
+		lines = append(append(lines, line...), '\n')
+	}
+
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	// A single write per batch, so a batch is appended whole.
+	if _, err := f.file.Write(lines); err != nil {
+		return fmt.Errorf("[FileStore::Add] error writing %s: %w", f.path, err)
+	}
+	f.records = append(f.records, records...)
+	return nil
+}
+
+// Close closes the file of the store.
+func (f *FileStore) Close() error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return f.file.Close()
+}
@@ -0,0 +1,64 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+)
+
+// Log formats supported by New.
+const (
+	FormatJSON   = "json"
+	FormatLogfmt = "logfmt"
+)
+
+// level is the level of every logger created by New, so it can be changed with SetLevel.
+var level slog.LevelVar
+
+// New creates a logger writing to w in the given format ("json" or "logfmt", default "json")
+// at the given level ("debug", "info", "warn" or "error", default "info").
+func New(w io.Writer, format, lvl string) (*slog.Logger, error) {
+	if err := SetLevel(lvl); err != nil {
+		return nil, err
+	}
+	opts := &slog.HandlerOptions{Level: &level}
+
+	switch strings.ToLower(format) {
+	case "", FormatJSON:
+		return slog.New(slog.NewJSONHandler(w, opts)), nil
+	case FormatLogfmt:
+		return slog.New(slog.NewTextHandler(w, opts)), nil
+	default:
+		return nil, fmt.Errorf("invalid log format %q, expected %q or %q", format, FormatJSON, FormatLogfmt)
+	}
+}
+
+// SetLevel changes the level ("debug", "info", "warn" or "error", default "info") of the loggers created by New.
+func SetLevel(lvl string) error {
+	var parsed slog.Level
+	if lvl != "" {
+		if err := parsed.UnmarshalText([]byte(lvl)); err != nil {
+			return fmt.Errorf("invalid log level %q", lvl)
+		}
+	}
+	level.Set(parsed)
+	return nil
+}
+
+// contextKey is the context key of the logger.
+type contextKey struct{}
+
+// WithLogger returns a copy of ctx carrying the logger.
+func WithLogger(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, contextKey{}, logger)
+}
+
+// FromContext returns the logger carried by ctx, or the default logger.
+func FromContext(ctx context.Context) *slog.Logger {
+	if logger, ok := ctx.Value(contextKey{}).(*slog.Logger); ok {
+		return logger
+	}
+	return slog.Default()
+}
@@ -0,0 +1,396 @@
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Spec is the part of an OpenAPI document describing request bodies, e.g. the spec of the Livepeer AI runner.
+type Spec struct {
+	Paths      map[string]PathItem `yaml:"paths"`
+	Components struct {
+		Schemas map[string]*Schema `yaml:"schemas"`
+	} `yaml:"components"`
+}
+
+// PathItem holds the operations of a path. Pipelines are only ever posted to.
+type PathItem struct {
+	Post *Operation `yaml:"post"`
+}
+
+// Operation is an API operation with its request body.
+type Operation struct {
+	RequestBody *RequestBody `yaml:"requestBody"`
+}
+
+// RequestBody maps the content types accepted by an operation to the schema of their body.
+type RequestBody struct {
+	Content map[string]MediaType `yaml:"content"`
+}
+
+// MediaType is the schema of a request body of a given content type.
+type MediaType struct {
+	Schema *Schema `yaml:"schema"`
+}
+
+// Schema is the subset of a JSON schema needed to check request parameters.
+// Type is a type name or, in OpenAPI 3.1, a list of type names.
+type Schema struct {
+	Ref              string             `yaml:"$ref"`
+	Type             interface{}        `yaml:"type"`
+	Format           string             `yaml:"format"`
+	ContentMediaType string             `yaml:"contentMediaType"`
+	Properties       map[string]*Schema `yaml:"properties"`
+	Required         []string           `yaml:"required"`
+	Items            *Schema            `yaml:"items"`
+	Enum             []interface{}      `yaml:"enum"`
+	Minimum          *float64           `yaml:"minimum"`
+	Maximum          *float64           `yaml:"maximum"`
+	AnyOf            []*Schema          `yaml:"anyOf"`
+	OneOf            []*Schema          `yaml:"oneOf"`
+	AllOf            []*Schema          `yaml:"allOf"`
+}
+
+// Load reads an OpenAPI document in JSON or YAML format.
+func Load(filePath string) (*Spec, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("[Load] error reading spec: %w", err)
+	}
+	// JSON is valid YAML, so both formats decode the same way.
+	var spec Spec
+	if err := yaml.Unmarshal(data, &spec); err != nil {
+		return nil, fmt.Errorf("[Load] error parsing spec %s: %w", filePath, err)
+	}
+	if len(spec.Paths) == 0 {
+		return nil, fmt.Errorf("[Load] spec %s has no paths", filePath)
+	}
+	return &spec, nil
+}
+
+// Check checks a request against the schema of the POST operation of the path for the content type.
+// Parameters are the values sent in the body and files the form fields sent as files, from the pipeline assets.
+// It reports unknown parameters, with the closest known name, values of the wrong type or out of range,
+// files sent in non-file fields and missing required fields. model_id is sent by the tester and isn't required here,
+// and required file fields are only checked when files are given, since the tester otherwise sends default assets.
+// Multipart values are sent as text, so strings holding a number or boolean match numeric and boolean fields,
+// and templated values, containing "{{", are only checked by name.
+func (s *Spec) Check(path, contentType string, parameters map[string]interface{}, files []string) []error {
+	path = "/" + strings.TrimPrefix(path, "/")
+	item, exists := s.Paths[path]
+	if !exists || item.Post == nil {
+		return []error{fmt.Errorf("no POST operation for %s in the OpenAPI spec", path)}
+	}
+	if item.Post.RequestBody == nil {
+		return []error{fmt.Errorf("POST %s has no request body in the OpenAPI spec", path)}
+	}
+	media, exists := item.Post.RequestBody.Content[contentType]
+	if !exists {
+		accepted := make([]string, 0, len(item.Post.RequestBody.Content))
+		for ct := range item.Post.RequestBody.Content {
+			accepted = append(accepted, ct)
+		}
+		sort.Strings(accepted)
+		return []error{fmt.Errorf("POST %s doesn't accept %s, expected one of [%s]", path, contentType, strings.Join(accepted, ", "))}
+	}
+	body := s.resolve(media.Schema)
+	if body == nil {
+		return nil
+	}
+	multipart := contentType != "application/json"
+
+	var errs []error
+	for _, name := range sortedNames(parameters) {
+		property, exists := body.Properties[name]
+		if !exists {
+			errs = append(errs, unknownField("parameter", name, body.Properties))
+			continue
+		}
+		if err := s.checkValue(parameters[name], property, multipart); err != nil {
+			errs = append(errs, fmt.Errorf("parameter %q: %w", name, err))
+		}
+	}
+	sort.Strings(files)
+	for _, name := range files {
+		property, exists := body.Properties[name]
+		if !exists {
+			errs = append(errs, unknownField("file field", name, body.Properties))
+			continue
+		}
+		if !s.isFile(property) {
+			errs = append(errs, fmt.Errorf("asset %q is sent as a file, but the field isn't a file", name))
+		}
+	}
+	for _, name := range body.Required {
+		if name == "model_id" {
+			continue
+		}
+		if _, set := parameters[name]; set {
+			continue
+		}
+		if s.isFile(body.Properties[name]) && (len(files) == 0 || contains(files, name)) {
+			continue
+		}
+		errs = append(errs, fmt.Errorf("required field %q is missing", name))
+	}
+	return errs
+}
+
+// checkValue checks a parameter value against its schema. A value matches an anyOf or oneOf schema
+// when it matches one of the alternatives, and an allOf schema when it matches all of them.
+func (s *Spec) checkValue(value interface{}, schema *Schema, multipart bool) error {
+	schema = s.resolve(schema)
+	if schema == nil {
+		return nil
+	}
+	if text, ok := value.(string); ok && strings.Contains(text, "{{") {
+		return nil
+	}
+	for _, sub := range schema.AllOf {
+		if err := s.checkValue(value, sub, multipart); err != nil {
+			return err
+		}
+	}
+	if alternatives := append(append([]*Schema(nil), schema.AnyOf...), schema.OneOf...); len(alternatives) > 0 {
+		var first error
+		for _, alternative := range alternatives {
+			err := s.checkValue(value, alternative, multipart)
+			if err == nil {
+				return nil
+			}
+			if first == nil {
+				first = err
+			}
+		}
+		return first
+	}
+
+	types := schemaTypes(schema)
+	if len(types) > 0 {
+		matched := false
+		for _, t := range types {
+			if matchesType(value, t, multipart) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return fmt.Errorf("expected %s, got %s", strings.Join(types, " or "), describe(value))
+		}
+	}
+	if len(schema.Enum) > 0 {
+		found := false
+		for _, allowed := range schema.Enum {
+			if fmt.Sprint(allowed) == fmt.Sprint(value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("%s is not one of %v", describe(value), schema.Enum)
+		}
+	}
+	if number, ok := toNumber(value, multipart); ok {
+		if schema.Minimum != nil && number < *schema.Minimum {
+			return fmt.Errorf("%v is below the minimum of %v", number, *schema.Minimum)
+		}
+		if schema.Maximum != nil && number > *schema.Maximum {
+			return fmt.Errorf("%v is above the maximum of %v", number, *schema.Maximum)
+		}
+	}
+	if list, ok := value.([]interface{}); ok && schema.Items != nil {
+		for i, item := range list {
+			if err := s.checkValue(item, schema.Items, multipart); err != nil {
+				return fmt.Errorf("item %d: %w", i, err)
+			}
+		}
+	}
+	return nil
+}
+
+// resolve follows the $ref of the schema to the components of the spec.
+func (s *Spec) resolve(schema *Schema) *Schema {
+	for depth := 0; schema != nil && schema.Ref != "" && depth < 32; depth++ {
+		name := strings.TrimPrefix(schema.Ref, "#/components/schemas/")
+		schema = s.Components.Schemas[name]
+	}
+	return schema
+}
+
+// isFile reports whether the schema describes an uploaded file.
+func (s *Spec) isFile(schema *Schema) bool {
+	schema = s.resolve(schema)
+	if schema == nil {
+		return false
+	}
+	if schema.Format == "binary" || schema.ContentMediaType != "" {
+		return true
+	}
+	for _, alternative := range append(append([]*Schema(nil), schema.AnyOf...), schema.OneOf...) {
+		if s.isFile(alternative) {
+			return true
+		}
+	}
+	return false
+}
+
+// schemaTypes returns the type names of the schema, of which the value must match one.
+func schemaTypes(schema *Schema) []string {
+	switch t := schema.Type.(type) {
+	case string:
+		return []string{t}
+	case []interface{}:
+		types := make([]string, 0, len(t))
+		for _, name := range t {
+			types = append(types, fmt.Sprint(name))
+		}
+		return types
+	}
+	return nil
+}
+
+// matchesType reports whether the value is of the JSON schema type. Multipart values are sent as text.
+func matchesType(value interface{}, schemaType string, multipart bool) bool {
+	if text, ok := value.(string); ok && multipart && schemaType != "string" {
+		switch schemaType {
+		case "integer":
+			_, err := strconv.ParseInt(strings.TrimSpace(text), 10, 64)
+			return err == nil
+		case "number":
+			_, err := strconv.ParseFloat(strings.TrimSpace(text), 64)
+			return err == nil
+		case "boolean":
+			_, err := strconv.ParseBool(strings.TrimSpace(text))
+			return err == nil
+		}
+	}
+	switch schemaType {
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "integer":
+		number, ok := toNumber(value, false)
+		return ok && number == math.Trunc(number)
+	case "number":
+		_, ok := toNumber(value, false)
+		return ok
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "array":
+		_, ok := value.([]interface{})
+		return ok
+	case "object":
+		_, ok := value.(map[string]interface{})
+		return ok
+	case "null":
+		return value == nil
+	}
+	return true
+}
+
+// toNumber returns the value as a number, parsing text when the value is sent as multipart form data.
+func toNumber(value interface{}, multipart bool) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case json.Number:
+		number, err := v.Float64()
+		return number, err == nil
+	case string:
+		if multipart {
+			number, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+			return number, err == nil
+		}
+	}
+	return 0, false
+}
+
+// describe names the JSON type of the value, with the value itself, for error messages.
+func describe(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case string:
+		return fmt.Sprintf("string %q", value)
+	case bool:
+		return fmt.Sprintf("boolean %v", value)
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	if _, ok := toNumber(value, false); ok {
+		return fmt.Sprintf("number %v", value)
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+// unknownField reports a field missing from the schema, suggesting the closest known field for typos.
+func unknownField(kind, name string, properties map[string]*Schema) error {
+	best, bestDistance := "", -1
+	for known := range properties {
+		d := distance(name, known)
+		if bestDistance < 0 || d < bestDistance || (d == bestDistance && known < best) {
+			best, bestDistance = known, d
+		}
+	}
+	if best != "" && bestDistance <= 1+len(name)/4 {
+		return fmt.Errorf("unknown %s %q, did you mean %q?", kind, name, best)
+	}
+	return fmt.Errorf("unknown %s %q, expected one of [%s]", kind, name, strings.Join(sortedNames(properties), ", "))
+}
+
+// distance is the Levenshtein distance between two names.
+func distance(a, b string) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(b)]
+}
+
+// sortedNames returns the keys of the map, sorted.
+func sortedNames[V any](m map[string]V) []string {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// contains reports whether the list holds the value.
+func contains(list []string, value string) bool {
+	for _, v := range list {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
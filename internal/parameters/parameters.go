@@ -0,0 +1,140 @@
+package parameters
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// Context holds the job values that parameter templates can reference,
+// e.g. "{{ .Orchestrator }}" or "{{ .Timestamp }}".
+type Context struct {
+	Region       string // Region of the job tester.
+	Orchestrator string // Ethereum address of the orchestrator being tested.
+	ServiceURI   string // Service URI of the orchestrator being tested.
+	Pipeline     string // Pipeline name as advertised by the gateway.
+	Model        string // Model ID being tested.
+	Timestamp    int64  // Unix time at which the job was created.
+}
+
+// Renderer renders templated pipeline parameters.
+// String values containing "{{" are executed as Go templates with the job Context as data
+// and the functions below available:
+//
+//	pick "a" "b" ...   a random argument, e.g. a prompt from a list
+//	randInt min max    a random integer in [min, max]
+//	randFloat min max  a random float in [min, max)
+//	seed               a random non-negative 32-bit seed
+//	now                the current unix time
+//
+// When a whole value is a single template and renders to a number or boolean,
+// the rendered value keeps that type so numeric fields stay numeric in the request.
+type Renderer struct {
+	rnd *rand.Rand
+}
+
+// NewRenderer creates a Renderer backed by a time-seeded random source.
+// A Renderer is not safe for concurrent use.
+func NewRenderer() *Renderer {
+	return &Renderer{rnd: rand.New(rand.NewSource(time.Now().UnixNano()))}
+}
+
+// Render returns a copy of params with every templated value rendered for the given context.
+// Nested objects and arrays are rendered recursively; the input map is never modified.
+func (r *Renderer) Render(params map[string]interface{}, ctx Context) (map[string]interface{}, error) {
+	rendered := make(map[string]interface{}, len(params))
+	for key, value := range params {
+		v, err := r.renderValue(value, ctx)
+		if err != nil {
+			return nil, fmt.Errorf("[Renderer::Render] parameter %q: %w", key, err)
+		}
+		rendered[key] = v
+	}
+	return rendered, nil
+}
+
+// renderValue renders a single parameter value of any JSON type.
+func (r *Renderer) renderValue(value interface{}, ctx Context) (interface{}, error) {
+	switch v := value.(type) {
+	case string:
+		return r.renderString(v, ctx)
+	case map[string]interface{}:
+		return r.Render(v, ctx)
+	case []interface{}:
+		items := make([]interface{}, len(v))
+		for i, item := range v {
+			rendered, err := r.renderValue(item, ctx)
+			if err != nil {
+				return nil, err
+			}
+			items[i] = rendered
+		}
+		return items, nil
+	default:
+		return value, nil
+	}
+}
+
+// renderString executes a templated string and converts whole-value templates to numbers or booleans.
+func (r *Renderer) renderString(value string, ctx Context) (interface{}, error) {
+	if !strings.Contains(value, "{{") {
+		return value, nil
+	}
+
+	tmpl, err := template.New("parameter").Funcs(r.funcs()).Option("missingkey=error").Parse(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid template %q: %w", value, err)
+	}
+	var out bytes.Buffer
+	if err := tmpl.Execute(&out, ctx); err != nil {
+		return nil, fmt.Errorf("failed to render template %q: %w", value, err)
+	}
+
+	trimmed := strings.TrimSpace(value)
+	if strings.HasPrefix(trimmed, "{{") && strings.HasSuffix(trimmed, "}}") && strings.Count(trimmed, "{{") == 1 {
+		var typed interface{}
+		if err := json.Unmarshal(out.Bytes(), &typed); err == nil {
+			switch typed.(type) {
+			case float64:
+				return json.Number(strings.TrimSpace(out.String())), nil
+			case bool:
+				return typed, nil
+			}
+		}
+	}
+	return out.String(), nil
+}
+
+// funcs returns the template functions, bound to the renderer's random source.
+func (r *Renderer) funcs() template.FuncMap {
+	return template.FuncMap{
+		"pick": func(choices ...interface{}) (interface{}, error) {
+			if len(choices) == 0 {
+				return nil, fmt.Errorf("pick requires at least one choice")
+			}
+			return choices[r.rnd.Intn(len(choices))], nil
+		},
+		"randInt": func(min, max int) (int, error) {
+			if max < min {
+				return 0, fmt.Errorf("randInt max %d is less than min %d", max, min)
+			}
+			return min + r.rnd.Intn(max-min+1), nil
+		},
+		"randFloat": func(min, max float64) (float64, error) {
+			if max < min {
+				return 0, fmt.Errorf("randFloat max %v is less than min %v", max, min)
+			}
+			return min + r.rnd.Float64()*(max-min), nil
+		},
+		"seed": func() int64 {
+			return r.rnd.Int63n(1 << 32)
+		},
+		"now": func() int64 {
+			return time.Now().Unix()
+		},
+	}
+}
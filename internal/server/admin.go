@@ -0,0 +1,191 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"livepeer-job-tester/internal/coordination"
+	"livepeer-job-tester/internal/types"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// adminHandlers registers the admin API, which lets operators run test rounds on demand:
+//
+//	GET    /admin/round    status and metrics of the running round, or the last one
+//	POST   /admin/round    start a round, optionally restricted by a RoundFilter body
+//	DELETE /admin/round    cancel the running round
+//	POST   /admin/jobs     test a single {"orchestrator", "pipeline", "model"} combination
+//	GET    /admin/results  recent job results, filtered by the orchestrator, pipeline and model query parameters
+//	GET    /admin/regions  comparison of the last rounds of the coordinated regions
+//	GET    /admin/config   the configuration in use, with secrets redacted, and the outcome of the reloads
+//
+// Every request must carry the configured admin token as a bearer token.
+func (ss *EmbeddedWebhookServer) adminHandlers(mux *http.ServeMux) {
+	// The admin token can't be reloaded, so it is read once.
+	adminToken := ss.config.AdminToken
+	mux.HandleFunc("/admin/round", requireAdmin(adminToken, ss.handleAdminRound))
+	mux.HandleFunc("/admin/jobs", requireAdmin(adminToken, ss.handleAdminJobs))
+	mux.HandleFunc("/admin/results", requireAdmin(adminToken, ss.handleAdminResults))
+	mux.HandleFunc("/admin/regions", requireAdmin(adminToken, ss.handleAdminRegions))
+	mux.HandleFunc("/admin/config", requireAdmin(adminToken, ss.handleAdminConfig))
+}
+
+// requireAdmin wraps an admin handler, rejecting requests without the admin bearer token.
+func requireAdmin(adminToken string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || !secretEqual(token, adminToken) {
+			slog.Warn("Rejected admin request", "remote_addr", r.RemoteAddr, "path", r.URL.Path, "reason", "invalid admin token")
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeJSONError(w, http.StatusUnauthorized, errors.New("invalid admin token"))
+			return
+		}
+		next(w, r)
+	}
+}
+
+// handleAdminRound shows, starts or cancels a test round.
+func (ss *EmbeddedWebhookServer) handleAdminRound(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET":
+		writeJSON(w, http.StatusOK, ss.RoundStatus())
+	case "POST":
+		var filter RoundFilter
+		if err := decodeJSONBody(r, &filter); err != nil {
+			writeJSONError(w, http.StatusBadRequest, err)
+			return
+		}
+		ss.startRound(w, filter)
+	case "DELETE":
+		if !ss.CancelRound() {
+			writeJSONError(w, http.StatusConflict, errors.New("no test round is running"))
+			return
+		}
+		writeJSON(w, http.StatusAccepted, ss.RoundStatus())
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// handleAdminJobs starts a round testing a single orchestrator, pipeline and model.
+func (ss *EmbeddedWebhookServer) handleAdminJobs(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	var job struct {
+		Orchestrator string `json:"orchestrator"`
+		Pipeline     string `json:"pipeline"`
+		Model        string `json:"model"`
+	}
+	if err := decodeJSONBody(r, &job); err != nil {
+		writeJSONError(w, http.StatusBadRequest, err)
+		return
+	}
+	if job.Orchestrator == "" || job.Pipeline == "" || job.Model == "" {
+		writeJSONError(w, http.StatusBadRequest, errors.New("orchestrator, pipeline and model are required"))
+		return
+	}
+	ss.startRound(w, RoundFilter{
+		Orchestrators: []string{job.Orchestrator},
+		Pipelines:     []string{job.Pipeline},
+		Models:        []string{job.Model},
+	})
+}
+
+// handleAdminResults lists recent job results, newest first.
+func (ss *EmbeddedWebhookServer) handleAdminResults(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	query := r.URL.Query()
+	limit := 50
+	if value := query.Get("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 {
+			writeJSONError(w, http.StatusBadRequest, fmt.Errorf("invalid limit: %q", value))
+			return
+		}
+		limit = n
+	}
+
+	filter := RoundFilter{}
+	if orch := query.Get("orchestrator"); orch != "" {
+		filter.Orchestrators = []string{orch}
+	}
+	if pipeline := query.Get("pipeline"); pipeline != "" {
+		filter.Pipelines = []string{pipeline}
+	}
+	if model := query.Get("model"); model != "" {
+		filter.Models = []string{model}
+	}
+	results := ss.results.Recent(limit, func(stats types.Stats) bool {
+		return filter.matchOrchestrator(stats.Orchestrator, "") && filter.matchPipeline(stats.Pipeline) && filter.matchModel(stats.Model)
+	})
+	writeJSON(w, http.StatusOK, results)
+}
+
+// handleAdminRegions compares the last rounds of the coordinated regions.
+func (ss *EmbeddedWebhookServer) handleAdminRegions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if ss.coordination == nil {
+		writeJSONError(w, http.StatusNotFound, errors.New("coordination is not configured"))
+		return
+	}
+	summaries, err := ss.coordination.Summaries(r.Context())
+	if err != nil {
+		writeJSONError(w, http.StatusBadGateway, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, coordination.Compare(summaries))
+}
+
+// startRound starts a test round in the background and writes its status.
+func (ss *EmbeddedWebhookServer) startRound(w http.ResponseWriter, filter RoundFilter) {
+	status, err := ss.StartRound(filter)
+	if errors.Is(err, ErrRoundInProgress) {
+		writeJSONError(w, http.StatusConflict, err)
+		return
+	}
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusAccepted, status)
+}
+
+// decodeJSONBody decodes the JSON request body into v. An empty body leaves v unchanged.
+func decodeJSONBody(r *http.Request, v interface{}) error {
+	decoder := json.NewDecoder(io.LimitReader(r.Body, 1<<20))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil && err != io.EOF {
+		return fmt.Errorf("invalid request body: %w", err)
+	}
+	return nil
+}
+
+// writeJSON writes v as a JSON response with the status code.
+func writeJSON(w http.ResponseWriter, statusCode int, v interface{}) {
+	res, err := json.Marshal(v)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(err.Error()))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	w.Write(res)
+}
+
+// writeJSONError writes an {"error": "..."} JSON response with the status code.
+func writeJSONError(w http.ResponseWriter, statusCode int, err error) {
+	writeJSON(w, statusCode, map[string]string{"error": err.Error()})
+}
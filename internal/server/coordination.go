@@ -0,0 +1,135 @@
+package server
+
+import (
+	"context"
+	"livepeer-job-tester/internal/coordination"
+	"livepeer-job-tester/internal/logging"
+	"sort"
+	"time"
+)
+
+// leasePollInterval is how often a coordinated round retries the orchestrators leased by other regions.
+const leasePollInterval = 10 * time.Second
+
+// orchestratorGroup holds the planned jobs of one orchestrator, which are tested under a single lease.
+type orchestratorGroup struct {
+	ethAddress string
+	jobs       []plannedJob
+}
+
+// SetCoordination makes the server's rounds coordinate with the testers of other regions through the store,
+// configured by the coordination settings. It must be called before the first round.
+func (ss *EmbeddedWebhookServer) SetCoordination(store coordination.Store) {
+	ss.coordination = store
+}
+
+// sendJobs sends the planned jobs in order, stopping when the round is cancelled.
+func (ss *EmbeddedWebhookServer) sendJobs(ctx context.Context, jobs []plannedJob) {
+	for _, job := range jobs {
+		if ctx.Err() != nil {
+			logging.FromContext(ctx).Warn("Test round cancelled")
+			return
+		}
+		// Failed jobs are logged by sendTestJob.
+		_ = ss.sendTestJob(ctx, job.ethAddress, job.serviceURI, job.pipeline, job.model, job.warm)
+	}
+}
+
+// sendCoordinatedJobs sends the planned jobs one orchestrator at a time, holding the orchestrator's lease so
+// no other region tests it meanwhile. Orchestrators are sorted by address and the order is staggered by region.
+// Orchestrators leased by another region are retried after the others, and skipped once the wait timeout
+// has passed. When the store is unavailable, orchestrators are tested without a lease.
+func (ss *EmbeddedWebhookServer) sendCoordinatedJobs(ctx context.Context, r *round, jobs []plannedJob) {
+	logger := logging.FromContext(ctx)
+	settings := ss.config.Coordination
+	// The durations were checked when the configuration was loaded.
+	ttl, _ := settings.LeaseDuration()
+	wait, _ := settings.WaitDuration()
+	holder := ss.config.Region + "/" + r.status.ID
+	deadline := time.Now().Add(wait)
+
+	pending := coordination.Stagger(groupJobs(jobs), settings.Regions, ss.config.Region)
+	for len(pending) > 0 {
+		var leased []orchestratorGroup
+		for _, group := range pending {
+			if ctx.Err() != nil {
+				logger.Warn("Test round cancelled")
+				return
+			}
+			release, acquired, err := coordination.Hold(ctx, ss.coordination, "orchestrator/"+group.ethAddress, holder, ttl)
+			if err != nil {
+				logger.Warn("Coordination store unavailable, testing without a lease", "orchestrator", group.ethAddress, "err", err)
+			} else if !acquired {
+				logger.Info("Orchestrator tested by another region, retrying later", "orchestrator", group.ethAddress)
+				leased = append(leased, group)
+				continue
+			}
+			ss.sendJobs(ctx, group.jobs)
+			release()
+		}
+		if len(leased) == 0 {
+			return
+		}
+		if time.Now().After(deadline) {
+			for _, group := range leased {
+				logger.Warn("Skipping orchestrator, still tested by another region", "orchestrator", group.ethAddress, "jobs", len(group.jobs))
+			}
+			return
+		}
+		select {
+		case <-ctx.Done():
+			logger.Warn("Test round cancelled")
+			return
+		case <-time.After(leasePollInterval):
+		}
+		pending = leased
+	}
+}
+
+// groupJobs groups the planned jobs by orchestrator, sorted by address so that every region
+// staggers the same list.
+func groupJobs(jobs []plannedJob) []orchestratorGroup {
+	var groups []orchestratorGroup
+	index := make(map[string]int)
+	for _, job := range jobs {
+		i, exists := index[job.ethAddress]
+		if !exists {
+			i = len(groups)
+			index[job.ethAddress] = i
+			groups = append(groups, orchestratorGroup{ethAddress: job.ethAddress})
+		}
+		groups[i].jobs = append(groups[i].jobs, job)
+	}
+	sort.SliceStable(groups, func(i, j int) bool { return groups[i].ethAddress < groups[j].ethAddress })
+	return groups
+}
+
+// compareRegions publishes the round summary of the region to the coordination store and logs how
+// the last rounds of every region compare.
+func (ss *EmbeddedWebhookServer) compareRegions(ctx context.Context, r *round) {
+	logger := logging.FromContext(ctx)
+	// The summary is published even when the round was cancelled.
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 30*time.Second)
+	defer cancel()
+
+	summary := coordination.RegionSummary{
+		Region:        ss.config.Region,
+		RoundID:       r.status.ID,
+		StartedAt:     r.status.StartedAt,
+		FinishedAt:    time.Now().Unix(),
+		Metrics:       ss.jobTesterMetrics.Summary(),
+		Orchestrators: ss.jobTesterMetrics.Orchestrators(),
+	}
+	if err := ss.coordination.PutSummary(ctx, summary); err != nil {
+		logger.Warn("Failed to publish round summary", "err", err)
+		return
+	}
+	summaries, err := ss.coordination.Summaries(ctx)
+	if err != nil {
+		logger.Warn("Failed to fetch region summaries", "err", err)
+		return
+	}
+	comparison := coordination.Compare(summaries)
+	logger.Info("Region comparison", "regions", comparison.Regions)
+	logger.Debug("Orchestrator comparison by region", "orchestrators", comparison.Orchestrators)
+}
@@ -0,0 +1,135 @@
+package server
+
+import (
+	_ "embed"
+	"net/http"
+	"sort"
+)
+
+// dashboardHTML is the page of the built-in dashboard. It polls /dashboard/state to stay up to date.
+//
+//go:embed dashboard.html
+var dashboardHTML []byte
+
+// dashboardFeedSize is the number of recent results shown in the dashboard feed.
+const dashboardFeedSize = 50
+
+// dashboardState is the data shown by the dashboard: the current round, a feed of recent
+// results and the orchestrator by pipeline matrix of the latest results.
+type dashboardState struct {
+	Round  RoundStatus       `json:"round"`
+	Feed   []dashboardResult `json:"feed"`
+	Matrix dashboardMatrix   `json:"matrix"`
+}
+
+// dashboardResult is a compact job result for the dashboard feed.
+type dashboardResult struct {
+	Timestamp     int64   `json:"timestamp"`
+	Orchestrator  string  `json:"orchestrator"`
+	Pipeline      string  `json:"pipeline"`
+	Model         string  `json:"model"`
+	Scenario      string  `json:"scenario,omitempty"`
+	SuccessRate   float64 `json:"success_rate"`
+	RoundTripTime float64 `json:"round_trip_time"`
+	Error         string  `json:"error,omitempty"`
+}
+
+// dashboardMatrix holds the latest results per orchestrator and pipeline.
+// Cells are keyed by orchestrator address, then by pipeline.
+type dashboardMatrix struct {
+	Orchestrators []string                             `json:"orchestrators"`
+	Pipelines     []string                             `json:"pipelines"`
+	Cells         map[string]map[string]*dashboardCell `json:"cells"`
+}
+
+// dashboardCell counts the models and scenarios of a pipeline whose latest job passed on an orchestrator.
+type dashboardCell struct {
+	Passed    int   `json:"passed"`
+	Total     int   `json:"total"`
+	Timestamp int64 `json:"timestamp"`
+}
+
+// handleDashboard serves the dashboard page.
+func (ss *EmbeddedWebhookServer) handleDashboard(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(dashboardHTML)
+}
+
+// handleDashboardState serves the dashboard data in JSON format.
+func (ss *EmbeddedWebhookServer) handleDashboardState(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	writeJSON(w, http.StatusOK, ss.dashboardState())
+}
+
+// dashboardState builds the dashboard data from the round status and the result history.
+func (ss *EmbeddedWebhookServer) dashboardState() dashboardState {
+	state := dashboardState{Round: ss.RoundStatus(), Feed: make([]dashboardResult, 0)}
+
+	for _, stats := range ss.results.Recent(dashboardFeedSize, nil) {
+		result := dashboardResult{
+			Timestamp:     stats.Timestamp,
+			Orchestrator:  stats.Orchestrator,
+			Pipeline:      stats.Pipeline,
+			Model:         stats.Model,
+			Scenario:      stats.Scenario,
+			SuccessRate:   stats.SuccessRate,
+			RoundTripTime: stats.RoundTripTime,
+		}
+		if len(stats.Errors) > 0 {
+			result.Error = stats.Errors[0].ErrorCode + ": " + stats.Errors[0].Message
+		}
+		state.Feed = append(state.Feed, result)
+	}
+
+	// Every known orchestrator gets a row, even before its first job.
+	cells := make(map[string]map[string]*dashboardCell)
+	ss.lock.RLock()
+	for _, o := range ss.orchestrators {
+		cells[o.Address] = make(map[string]*dashboardCell)
+	}
+	ss.lock.RUnlock()
+
+	pipelines := make(map[string]bool)
+	for _, stats := range ss.results.Latest() {
+		row, ok := cells[stats.Orchestrator]
+		if !ok {
+			row = make(map[string]*dashboardCell)
+			cells[stats.Orchestrator] = row
+		}
+		cell, ok := row[stats.Pipeline]
+		if !ok {
+			cell = &dashboardCell{}
+			row[stats.Pipeline] = cell
+		}
+		cell.Total++
+		if stats.SuccessRate == 1 {
+			cell.Passed++
+		}
+		if stats.Timestamp > cell.Timestamp {
+			cell.Timestamp = stats.Timestamp
+		}
+		pipelines[stats.Pipeline] = true
+	}
+
+	state.Matrix.Cells = cells
+	state.Matrix.Orchestrators = sortedKeys(cells)
+	state.Matrix.Pipelines = sortedKeys(pipelines)
+	return state
+}
+
+// sortedKeys returns the keys of the map in sorted order.
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,113 @@
+package server
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"livepeer-job-tester/internal/types"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// llmEvent is a server-sent event of a streamed LLM response. Both the go-livepeer format
+// ({"chunk": "...", "tokens_used": n, "done": true}) and the OpenAI compatible format
+// ({"choices": [{"delta": {"content": "..."}, "finish_reason": "stop"}], "usage": {...}}) are supported.
+type llmEvent struct {
+	Chunk      string `json:"chunk"`
+	TokensUsed int    `json:"tokens_used"`
+	Done       bool   `json:"done"`
+	Choices    []struct {
+		Delta struct {
+			Content string `json:"content"`
+		} `json:"delta"`
+		FinishReason *string `json:"finish_reason"`
+	} `json:"choices"`
+	Usage *struct {
+		CompletionTokens int `json:"completion_tokens"`
+	} `json:"usage"`
+}
+
+// isEventStream reports whether the response is a server-sent event stream.
+func isEventStream(res *http.Response) bool {
+	mediaType, _, err := mime.ParseMediaType(res.Header.Get("Content-Type"))
+	return err == nil && mediaType == "text/event-stream"
+}
+
+// readLLMStream reads a streamed LLM response and measures time-to-first-token (from startTime),
+// tokens per second and total tokens. Token counts reported by the final event are preferred;
+// otherwise every content event counts as one token. It returns the raw stream body with the stats.
+func readLLMStream(body io.Reader, startTime time.Time) (string, *types.LLMStats, error) {
+	stats := &types.LLMStats{}
+	var raw strings.Builder
+	var firstToken, lastToken time.Time
+	counted, reported := 0, 0
+
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		raw.WriteString(line)
+		raw.WriteString("\n")
+
+		data, ok := strings.CutPrefix(line, "data:")
+		if !ok {
+			continue
+		}
+		data = strings.TrimSpace(data)
+		if data == "[DONE]" {
+			stats.StreamCompleted = true
+			continue
+		}
+
+		var event llmEvent
+		if err := json.Unmarshal([]byte(data), &event); err != nil {
+			return raw.String(), stats, fmt.Errorf("[readLLMStream] invalid stream event %q: %w", data, err)
+		}
+
+		content := event.Chunk
+		if content == "[DONE]" {
+			content = ""
+			stats.StreamCompleted = true
+		}
+		for _, choice := range event.Choices {
+			content += choice.Delta.Content
+			if choice.FinishReason != nil && *choice.FinishReason != "" {
+				stats.StreamCompleted = true
+			}
+		}
+		if event.Done {
+			stats.StreamCompleted = true
+		}
+		if event.TokensUsed > 0 {
+			reported = event.TokensUsed
+		}
+		if event.Usage != nil && event.Usage.CompletionTokens > 0 {
+			reported = event.Usage.CompletionTokens
+		}
+
+		if content != "" {
+			now := time.Now()
+			if firstToken.IsZero() {
+				firstToken = now
+				stats.TimeToFirstToken = now.Sub(startTime).Seconds()
+			}
+			lastToken = now
+			counted++
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return raw.String(), stats, fmt.Errorf("[readLLMStream] %w", err)
+	}
+
+	stats.TotalTokens = counted
+	if reported > 0 {
+		stats.TotalTokens = reported
+	}
+	if generation := lastToken.Sub(firstToken).Seconds(); generation > 0 && stats.TotalTokens > 1 {
+		stats.TokensPerSecond = float64(stats.TotalTokens-1) / generation
+	}
+	return raw.String(), stats, nil
+}
@@ -0,0 +1,71 @@
+package server
+
+import (
+	"livepeer-job-tester/internal/config"
+	"livepeer-job-tester/internal/services"
+	"log/slog"
+	"net/http"
+)
+
+// pendingConfig is a reloaded configuration waiting for the next round, with the HTTP client
+// and Livepeer service built for it.
+type pendingConfig struct {
+	config          *config.Config
+	client          *http.Client
+	livepeerService services.LivepeerService
+}
+
+// Reload replaces the configuration, the HTTP client and the Livepeer service at the start of the next round,
+// so a running round finishes with the configuration it started with. Settings served by the web server, like
+// the webhook security and the admin token, can't be reloaded; see config.ChangesRequiringRestart.
+func (ss *EmbeddedWebhookServer) Reload(cfg *config.Config, client *http.Client, livepeerService services.LivepeerService) {
+	ss.lock.Lock()
+	defer ss.lock.Unlock()
+	ss.pending = &pendingConfig{config: cfg, client: client, livepeerService: livepeerService}
+	ss.reloads.RecordReload()
+}
+
+// RejectReload records a reload that was rejected; the server keeps its configuration.
+func (ss *EmbeddedWebhookServer) RejectReload(err error) {
+	ss.reloads.RecordRejectedReload(err)
+}
+
+// applyPendingConfig swaps in the reloaded configuration, if any.
+// The caller must hold the lock, and no round may be running.
+func (ss *EmbeddedWebhookServer) applyPendingConfig() {
+	if ss.pending == nil {
+		return
+	}
+	// The stats still held back by the replaced service are posted in the background.
+	if flusher, ok := ss.livepeerService.(services.StatsFlusher); ok {
+		go func() {
+			if err := flusher.FlushStats(); err != nil {
+				slog.Error("Failed to post batched stats", "err", err)
+			}
+		}()
+	}
+	ss.config = ss.pending.config
+	ss.client = ss.pending.client
+	ss.livepeerService = ss.pending.livepeerService
+	ss.pending = nil
+	slog.Info("Applied reloaded configuration", "region", ss.config.Region, "pipelines", len(ss.config.Pipelines))
+}
+
+// configState describes the configuration in use, with secrets redacted, and the outcome of the reloads.
+type configState struct {
+	Config        *config.Config                `json:"config"`
+	ReloadPending bool                          `json:"reload_pending"`
+	Reloads       *services.ConfigReloadMetrics `json:"reloads"`
+}
+
+// handleAdminConfig shows the configuration in use and the outcome of the reloads.
+func (ss *EmbeddedWebhookServer) handleAdminConfig(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	ss.lock.RLock()
+	state := configState{Config: ss.config.Redacted(), ReloadPending: ss.pending != nil, Reloads: ss.reloads}
+	ss.lock.RUnlock()
+	writeJSON(w, http.StatusOK, state)
+}
@@ -0,0 +1,324 @@
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"livepeer-job-tester/internal/config"
+	"livepeer-job-tester/internal/logging"
+	"livepeer-job-tester/internal/services"
+	"livepeer-job-tester/internal/tracing"
+	"livepeer-job-tester/internal/types"
+	"log/slog"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// resultHistoryLimit is the number of recent job results kept for the admin API.
+const resultHistoryLimit = 500
+
+// ErrRoundInProgress is returned when a test round is started while another one is running.
+// Only one round runs at a time, since the gateway can only be pinned to one orchestrator.
+var ErrRoundInProgress = errors.New("a test round is already running")
+
+// RoundFilter restricts a test round to some orchestrators, pipelines and models.
+// Orchestrators match by eth address or service URI, pipelines by canonical ID and models by name.
+// An empty list matches everything.
+type RoundFilter struct {
+	Orchestrators []string `json:"orchestrators,omitempty"`
+	Pipelines     []string `json:"pipelines,omitempty"`
+	Models        []string `json:"models,omitempty"`
+}
+
+// matchOrchestrator reports whether the filter accepts the orchestrator.
+func (f RoundFilter) matchOrchestrator(ethAddress, serviceURI string) bool {
+	if len(f.Orchestrators) == 0 {
+		return true
+	}
+	for _, orch := range f.Orchestrators {
+		if orchestratorMatches(orch, ethAddress, serviceURI) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchPipeline reports whether the filter accepts the pipeline capability type.
+func (f RoundFilter) matchPipeline(pipeline string) bool {
+	if len(f.Pipelines) == 0 {
+		return true
+	}
+	for _, p := range f.Pipelines {
+		if config.CanonicalPipelineID(p) == config.CanonicalPipelineID(pipeline) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchModel reports whether the filter accepts the model.
+func (f RoundFilter) matchModel(model string) bool {
+	if len(f.Models) == 0 {
+		return true
+	}
+	for _, m := range f.Models {
+		if strings.EqualFold(m, model) {
+			return true
+		}
+	}
+	return false
+}
+
+// RoundStatus describes the running test round, or the last one to finish.
+type RoundStatus struct {
+	ID         string                  `json:"id,omitempty"`
+	Running    bool                    `json:"running"`
+	Cancelled  bool                    `json:"cancelled"`
+	Filter     RoundFilter             `json:"filter"`
+	StartedAt  int64                   `json:"started_at,omitempty"`
+	FinishedAt int64                   `json:"finished_at,omitempty"`
+	Error      string                  `json:"error,omitempty"`
+	OrchToTest string                  `json:"orch_to_test"`
+	Metrics    services.MetricsSummary `json:"metrics"`
+}
+
+// round holds the state of a test round, guarded by the server lock.
+type round struct {
+	status RoundStatus
+	cancel context.CancelFunc
+}
+
+// plannedJob is an orchestrator, pipeline and model combination to test in a round.
+type plannedJob struct {
+	ethAddress string
+	serviceURI string
+	pipeline   string
+	model      string
+	warm       bool
+}
+
+// RunRound runs a test round restricted by the filter and waits for it to finish. Job metrics are reset
+// at the start of the round and a JSON report is logged at the end. The round stops early when the
+// context is cancelled or CancelRound is called. It returns ErrRoundInProgress if a round is already running.
+func (ss *EmbeddedWebhookServer) RunRound(ctx context.Context, filter RoundFilter) error {
+	ctx, r, err := ss.beginRound(ctx, filter)
+	if err != nil {
+		return err
+	}
+	err = ss.runRound(ctx, r)
+	ss.finishRound(ctx, r, err)
+	return err
+}
+
+// StartRound starts a test round restricted by the filter in the background and returns its status.
+// It returns ErrRoundInProgress if a round is already running.
+func (ss *EmbeddedWebhookServer) StartRound(filter RoundFilter) (RoundStatus, error) {
+	ctx, r, err := ss.beginRound(context.Background(), filter)
+	if err != nil {
+		return RoundStatus{}, err
+	}
+	go func() {
+		err := ss.runRound(ctx, r)
+		if err != nil {
+			logging.FromContext(ctx).Error("Test round failed", "err", err)
+		}
+		ss.finishRound(ctx, r, err)
+	}()
+	return ss.RoundStatus(), nil
+}
+
+// CancelRound cancels the running test round. The job in progress is abandoned and counted as a tester error.
+// It reports whether a round was running.
+func (ss *EmbeddedWebhookServer) CancelRound() bool {
+	ss.lock.Lock()
+	defer ss.lock.Unlock()
+	if ss.round == nil || !ss.round.status.Running {
+		return false
+	}
+	ss.round.cancel()
+	return true
+}
+
+// RoundStatus returns the status of the running test round, or of the last one to finish,
+// along with the current job metrics.
+func (ss *EmbeddedWebhookServer) RoundStatus() RoundStatus {
+	ss.lock.RLock()
+	var status RoundStatus
+	if ss.round != nil {
+		status = ss.round.status
+	}
+	status.OrchToTest = ss.orchToTest
+	ss.lock.RUnlock()
+
+	status.Metrics = ss.jobTesterMetrics.Summary()
+	return status
+}
+
+// beginRound registers a new running round, applies the reloaded configuration, if any, and resets the job metrics.
+func (ss *EmbeddedWebhookServer) beginRound(parent context.Context, filter RoundFilter) (context.Context, *round, error) {
+	id, err := newID()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ss.lock.Lock()
+	defer ss.lock.Unlock()
+	if ss.round != nil && ss.round.status.Running {
+		return nil, nil, ErrRoundInProgress
+	}
+	ss.applyPendingConfig()
+	ctx, cancel := context.WithCancel(parent)
+	ctx = logging.WithLogger(ctx, logging.FromContext(ctx).With("run_id", id, "region", ss.config.Region))
+	ss.round = &round{
+		status: RoundStatus{
+			ID:        id,
+			Running:   true,
+			Filter:    filter,
+			StartedAt: time.Now().Unix(),
+		},
+		cancel: cancel,
+	}
+	ss.jobTesterMetrics.Reset()
+	return ctx, ss.round, nil
+}
+
+// finishRound records the outcome of the round and releases the pinned orchestrator.
+func (ss *EmbeddedWebhookServer) finishRound(ctx context.Context, r *round, err error) {
+	ss.lock.Lock()
+	defer ss.lock.Unlock()
+	r.status.Running = false
+	r.status.Cancelled = ctx.Err() != nil
+	r.status.FinishedAt = time.Now().Unix()
+	if err != nil {
+		r.status.Error = err.Error()
+	}
+	r.cancel()
+	ss.orchToTest = ""
+}
+
+// runRound fetches orchestrators and pipelines from the Livepeer API and sends test jobs to each
+// orchestrator accepted by the round filter and the configured filter. Coordinated rounds also compare their results with the other regions.
+func (ss *EmbeddedWebhookServer) runRound(ctx context.Context, r *round) error {
+	filter := r.status.Filter
+	// The configured filter applies to every round, in addition to the round's own filter.
+	configFilter := RoundFilter{}
+	if ss.config.Filter != nil {
+		configFilter = RoundFilter(*ss.config.Filter)
+	}
+	ctx, span := tracing.Start(ctx, "round", trace.WithAttributes(attribute.String("round.id", r.status.ID), attribute.String("round.region", ss.config.Region)))
+	defer span.End()
+	logger := logging.FromContext(ctx)
+	logger.Info("Starting test round", "filter", filter, "config_filter", configFilter)
+
+	// Fetch orchestrators
+	orchestrators, err := ss.livepeerService.FetchOrchestrators()
+	if err != nil {
+		ss.jobTesterMetrics.IncrementTotalJobsTesterError()
+		return fmt.Errorf("failed to fetch orchestrators: %w", err)
+	}
+	logger.Info("Orchestrators found", "count", len(orchestrators))
+	ss.lock.Lock()
+	ss.orchestrators = orchestrators
+	ss.lock.Unlock()
+
+	// Fetch pipelines
+	pipelines, err := ss.livepeerService.FetchPipelines()
+	if err != nil {
+		ss.jobTesterMetrics.IncrementTotalJobsTesterError()
+		return fmt.Errorf("failed to fetch pipelines: %w", err)
+	}
+	orchestratorMap := make(map[string]types.OrchestratorCapability)
+
+	// Iterate over the Orchestrators slice and populate the map
+	for _, orchestrator := range pipelines.Orchestrators {
+		orchestratorMap[orchestrator.Address] = orchestrator
+	}
+
+	// Plan the jobs and calculate the total number of expected jobs.
+	var jobs []plannedJob
+	for _, o := range orchestrators {
+		ethAddress := o.Address
+		if !filter.matchOrchestrator(ethAddress, o.ServiceURI) || !configFilter.matchOrchestrator(ethAddress, o.ServiceURI) {
+			continue
+		}
+		if orchCapability, exists := orchestratorMap[ethAddress]; exists {
+			for _, pipeline := range orchCapability.Pipelines {
+				pipelineName := pipeline.Type
+				if !filter.matchPipeline(pipelineName) || !configFilter.matchPipeline(pipelineName) {
+					continue
+				}
+				cfgPipeline, found := ss.config.FindPipeline(pipelineName)
+				for _, model := range pipeline.Models {
+					modelName := model.Name
+					if !filter.matchModel(modelName) || !configFilter.matchModel(modelName) {
+						continue
+					}
+					if !found {
+						// Capabilities without pipeline config are reported, not counted as jobs.
+						logger.Warn("Untested capability, no pipeline configuration", "orchestrator", ethAddress, "pipeline", pipelineName, "model", modelName)
+						ss.jobTesterMetrics.RecordUntestedCapability(pipelineName)
+						continue
+					}
+					// Each configured scenario is a separate job.
+					scenarios := len(cfgPipeline.ResolvedScenarios())
+					logger.Debug("Expected jobs increment", "orchestrator", ethAddress, "pipeline", pipelineName, "model", modelName, "scenarios", scenarios)
+					for i := 0; i < scenarios; i++ {
+						ss.jobTesterMetrics.IncrementExpectedTotalJobs()
+					}
+					jobs = append(jobs, plannedJob{
+						ethAddress: ethAddress,
+						serviceURI: o.ServiceURI,
+						pipeline:   pipelineName,
+						model:      modelName,
+						warm:       model.Status.Warm > 0,
+					})
+				}
+			}
+		}
+	}
+	if len(jobs) == 0 && (len(filter.Orchestrators) > 0 || len(filter.Pipelines) > 0 || len(filter.Models) > 0) {
+		return fmt.Errorf("no orchestrator, pipeline and model matched the round filter")
+	}
+
+	// Send test jobs to orchestrators, taking turns with the other regions when coordinated.
+	if ss.coordination != nil {
+		ss.sendCoordinatedJobs(ctx, r, jobs)
+	} else {
+		ss.sendJobs(ctx, jobs)
+	}
+	// Post the stats still waiting in a batch, so every result of the round is sent when it ends.
+	ss.FlushStats(ctx)
+
+	// Log the report. The full report holds the request and response bodies, so it's only logged at debug level.
+	logger.Info("Job stats report", "metrics", ss.jobTesterMetrics.Summary())
+	if logger.Enabled(ctx, slog.LevelDebug) {
+		statsJSON, err := json.Marshal(ss.jobTesterMetrics)
+		if err != nil {
+			logger.Error("Error marshalling job stats to JSON", "err", err)
+			return err
+		}
+		logger.Debug("Full job stats report", "report", string(statsJSON))
+	}
+	if ss.coordination != nil {
+		ss.compareRegions(ctx, r)
+	}
+	if ctx.Err() != nil {
+		return fmt.Errorf("test round cancelled: %w", ctx.Err())
+	}
+	return nil
+}
+
+// newID returns a unique ID for a test round or job.
+func newID() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate ID: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -9,15 +9,23 @@ import (
 	"io"
 	"io/ioutil"
 	"livepeer-job-tester/internal/config"
+	"livepeer-job-tester/internal/coordination"
+	"livepeer-job-tester/internal/logging"
+	"livepeer-job-tester/internal/parameters"
 	"livepeer-job-tester/internal/services"
+	"livepeer-job-tester/internal/tracing"
 	"livepeer-job-tester/internal/types"
-	"log"
+	"livepeer-job-tester/internal/validation"
+	"log/slog"
 	"mime/multipart"
 	"net/http"
 	"os"
 	"strconv"
 	"sync"
 	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // ServerService defines the interface for starting the server and sending test jobs.
@@ -30,13 +38,19 @@ type ServerService interface {
 // EmbeddedWebhookServer represents the server responsible for managing job testing and orchestrator interactions.
 // It contains configuration, a client, orchestrators, and a metrics service for tracking job test results.
 type EmbeddedWebhookServer struct {
-	lock             sync.RWMutex               // Mutex to manage concurrent access to orchestrator data.
-	config           *config.Config             // Configuration for the server, including API endpoints and credentials.
-	livepeerService  services.LivepeerService   // Service to interact with Livepeer API for fetching orchestrators and pipelines.
-	client           *http.Client               // HTTP client for making requests.
-	orchestrators    []types.Orchestrator       // List of orchestrators fetched from the Livepeer API.
-	orchToTest       string                     // Currently selected orchestrator for testing.
-	jobTesterMetrics *services.JobTesterMetrics // Metrics service for tracking job tester results.
+	lock             sync.RWMutex                  // Mutex to manage concurrent access to orchestrator data.
+	config           *config.Config                // Configuration for the server, including API endpoints and credentials.
+	livepeerService  services.LivepeerService      // Service to interact with Livepeer API for fetching orchestrators and pipelines.
+	client           *http.Client                  // HTTP client for making requests.
+	orchestrators    []types.Orchestrator          // List of orchestrators fetched from the Livepeer API.
+	orchToTest       string                        // Currently selected orchestrator for testing.
+	jobSpan          trace.SpanContext             // Span of the job in progress, parent of the gateway webhook spans.
+	jobTesterMetrics *services.JobTesterMetrics    // Metrics service for tracking job tester results.
+	results          *services.ResultHistory       // Recent job results across test rounds, served by the admin API.
+	round            *round                        // The running test round, or the last one to finish.
+	coordination     coordination.Store            // Store shared with the testers of other regions, nil when not coordinated.
+	pending          *pendingConfig                // Reloaded configuration applied at the start of the next round.
+	reloads          *services.ConfigReloadMetrics // Outcome of the configuration reloads.
 }
 
 // NewEmbeddedWebhookServer creates a new instance of EmbeddedWebhookServer with the provided configuration, HTTP client, and Livepeer service.
@@ -51,19 +65,38 @@ func NewEmbeddedWebhookServer(
 		client:           client,
 		livepeerService:  livepeerService,
 		jobTesterMetrics: services.NewJobTesterMetrics(),
+		results:          services.NewResultHistory(resultHistoryLimit),
+		reloads:          services.NewConfigReloadMetrics(),
 	}
 }
 
-// StartServer starts the HTTP server and listens on the specified address.
+// StartServer starts the HTTP server and listens on the specified address, over HTTPS when a webhook certificate is configured.
 // It sets up the web server handlers and manages the shutdown process.
 func (ss *EmbeddedWebhookServer) StartServer(addr string) error {
+	return Serve(addr, ss.Handler(), ss.config.Webhook)
+}
+
+// Handler returns the HTTP handler of the server, serving the webhook, dashboard and admin API
+// under the configured path prefix.
+func (ss *EmbeddedWebhookServer) Handler() http.Handler {
+	mux := ss.webServerHandlers()
+	if ss.config.PathPrefix == "" {
+		return mux
+	}
+	prefixed := http.NewServeMux()
+	prefixed.Handle(ss.config.PathPrefix+"/", http.StripPrefix(ss.config.PathPrefix, mux))
+	return prefixed
+}
+
+// Serve listens on the specified address and serves the handler, over HTTPS when the webhook
+// settings have a certificate. Several servers can share a listener by combining their handlers.
+func Serve(addr string, handler http.Handler, webhook *config.Webhook) error {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	mux := ss.webServerHandlers()
 	srv := &http.Server{
 		Addr:    addr,
-		Handler: mux,
+		Handler: handler,
 	}
 
 	// Start a goroutine to handle graceful shutdown.
@@ -71,195 +104,398 @@ func (ss *EmbeddedWebhookServer) StartServer(addr string) error {
 		<-ctx.Done()
 		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 		defer cancel()
-		log.Println("[StartServer] Shutting down web server")
+		slog.Info("Shutting down web server")
 		if err := srv.Shutdown(shutdownCtx); err != nil {
-			log.Printf("[StartServer] failed to shutdown web server: %v\n", err)
+			slog.Error("Failed to shutdown web server", "err", err)
 		}
 	}()
 
-	log.Printf("[StartServer] Web server listening at %s\n", addr)
-	if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+	var err error
+	if webhook != nil && webhook.CertFile != "" {
+		slog.Info("Web server listening", "addr", addr, "tls", true)
+		err = srv.ListenAndServeTLS(webhook.CertFile, webhook.KeyFile)
+	} else {
+		slog.Info("Web server listening", "addr", addr)
+		err = srv.ListenAndServe()
+	}
+	if err != nil && err != http.ErrServerClosed {
 		return fmt.Errorf("[StartServer] ListenAndServe error: %w", err)
 	}
 	return nil
 }
 
-// RunTestJobs fetches orchestrators and pipelines from the Livepeer API and sends test jobs to each orchestrator.
-// It increments job metrics and generates a JSON report of the job tester results.
+// RunTestJobs runs a full test round: it fetches orchestrators and pipelines from the Livepeer API and sends
+// test jobs to each orchestrator. It increments job metrics and generates a JSON report of the job tester results.
 func (ss *EmbeddedWebhookServer) RunTestJobs() error {
-	// Fetch orchestrators
-	orchestrators, err := ss.livepeerService.FetchOrchestrators()
-	if err != nil {
+	return ss.RunRound(context.Background(), RoundFilter{})
+}
+
+// SendTestJob sends test jobs to the specified orchestrator and pipeline, including the model name and warm status.
+// Each scenario configured on the pipeline is sent as a separate job; a pipeline without scenarios sends one job.
+// The errors of all failed jobs are joined together.
+func (ss *EmbeddedWebhookServer) SendTestJob(orchEthAddr, orchServiceUri, pipeline, model string, modelIsWarm bool) error {
+	return ss.sendTestJob(context.Background(), orchEthAddr, orchServiceUri, pipeline, model, modelIsWarm)
+}
+
+// sendTestJob sends the test jobs of SendTestJob. Jobs stop early when the context is cancelled.
+// Each job pins the orchestrator and gets a job ID, and its log lines carry the job ID, orchestrator,
+// pipeline, model and scenario. Each job is traced as its own trace, linked to the round's span.
+func (ss *EmbeddedWebhookServer) sendTestJob(ctx context.Context, orchEthAddr, orchServiceUri, pipeline, model string, modelIsWarm bool) error {
+	// Find pipeline parameters from the config.
+	cfgPipeline, found := ss.config.FindPipeline(pipeline)
+	if !found {
+		ss.jobTesterMetrics.IncrementTotalJobs()
 		ss.jobTesterMetrics.IncrementTotalJobsTesterError()
-		return fmt.Errorf("failed to fetch orchestrators: %w", err)
+		return fmt.Errorf("[SendTestJob] pipeline not found in configuration file: %s", pipeline)
 	}
-	log.Println("[EmbeddedWebhookServer] Orchestrators Found ", len(orchestrators))
-	ss.orchestrators = orchestrators
 
-	// Fetch pipelines
-	pipelines, err := ss.livepeerService.FetchPipelines()
-	if err != nil {
-		ss.jobTesterMetrics.IncrementTotalJobsTesterError()
-		return fmt.Errorf("failed to fetch pipelines: %w", err)
-	}
-	orchestratorMap := make(map[string]types.OrchestratorCapability)
-
-	// Iterate over the Orchestrators slice and populate the map
-	for _, orchestrator := range pipelines.Orchestrators {
-		orchestratorMap[orchestrator.Address] = orchestrator
-	}
-	// Calculate the total number of expected jobs.
-	for _, o := range orchestrators {
-		ethAddress := o.Address
-		if orchCapability, exists := orchestratorMap[ethAddress]; exists {
-			for _, pipeline := range orchCapability.Pipelines {
-				pipelineName := pipeline.Type
-				for _, model := range pipeline.Models {
-					modelName := model.Name
-					log.Println("Total Expected jobs increment ", pipelineName, modelName)
-					ss.jobTesterMetrics.IncrementExpectedTotalJobs()
-				}
-			}
+	var errs []error
+	for _, scenario := range cfgPipeline.ResolvedScenarios() {
+		if ctx.Err() != nil {
+			errs = append(errs, fmt.Errorf("[SendTestJob] %w", ctx.Err()))
+			break
+		}
+		jobID, err := newID()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("[SendTestJob] %w", err))
+			break
+		}
+		logger := logging.FromContext(ctx).With(
+			"job_id", jobID,
+			"orchestrator", orchEthAddr,
+			"service_uri", orchServiceUri,
+			"pipeline", pipeline,
+			"model", model,
+			"scenario", scenario.Name,
+		)
+		jobCtx, span := tracing.Start(ctx, "job",
+			trace.WithNewRoot(),
+			trace.WithLinks(trace.LinkFromContext(ctx)),
+			trace.WithAttributes(tracing.JobAttributes(orchEthAddr, orchServiceUri, pipeline, model, scenario.Name)...),
+			trace.WithAttributes(attribute.String("job.id", jobID), attribute.Bool("job.model_warm", modelIsWarm)),
+		)
+		if span.SpanContext().IsValid() {
+			logger = logger.With("trace_id", span.SpanContext().TraceID().String())
+		}
+		jobCtx = logging.WithLogger(jobCtx, logger)
+		logger.Info("Sending test job", "warm", modelIsWarm)
+
+		// Pin the orchestrator, so the gateway webhook only returns this one.
+		_, pinSpan := tracing.Start(jobCtx, "orchestrator.pin")
+		ss.SetOrchToTest(orchServiceUri)
+		ss.setJobSpan(span.SpanContext())
+		pinSpan.End()
+
+		if cfgPipeline.IsStream() {
+			err = ss.sendStreamJob(jobCtx, orchEthAddr, orchServiceUri, pipeline, model, modelIsWarm, cfgPipeline, &scenario)
+		} else {
+			err = ss.sendScenarioJob(jobCtx, orchEthAddr, orchServiceUri, pipeline, model, modelIsWarm, cfgPipeline, &scenario)
 		}
+		ss.setJobSpan(trace.SpanContext{})
+		if err != nil {
+			logger.Error("Failed sending test job", "err", err)
+			errs = append(errs, err)
+		}
+		tracing.End(span, err)
 	}
+	return errors.Join(errs...)
+}
 
-	// Send test jobs to orchestrators
-	for _, o := range orchestrators {
-		ethAddress := o.Address
-		serviceURI := o.ServiceURI
-		if capability, exists := orchestratorMap[ethAddress]; exists {
-			for _, pipeline := range capability.Pipelines {
-				pipelineName := pipeline.Type
-				for _, model := range pipeline.Models {
-					modelName := model.Name
-					warmStatus := model.Status.Warm > 0
-					log.Printf("[EmbeddedWebhookServer] sending AI Test Region [%s] Orch: %s ServiceURI: %s  Pipeline: %v Model: %s Warm: %v\n", ss.config.Region, ethAddress, serviceURI, pipelineName, modelName, warmStatus)
-					ss.SetOrchToTest(serviceURI)
-					err := ss.SendTestJob(ethAddress, serviceURI, pipelineName, modelName, warmStatus)
-					if err != nil {
-						log.Printf("[EmbeddedWebhookServer] Failed sending test job. Region [%s] Orch: [%s] pipeline [%s] model [%s] - Err [%v]\n", ss.config.Region, ethAddress, pipelineName, modelName, err)
-					}
-				}
-			}
-		}
+// jobParameters merges the model overrides over the scenario parameters, renders the
+// templated values for the job and adds the model ID.
+func (ss *EmbeddedWebhookServer) jobParameters(cfgPipeline *config.Pipeline, scenario *config.Scenario, orchEthAddr, orchServiceUri, pipeline, model string, timestamp int64) (map[string]interface{}, error) {
+	overrides, err := cfgPipeline.ModelOverrides(model)
+	if err != nil {
+		return nil, err
+	}
+	jobParams := make(map[string]interface{}, len(scenario.Parameters)+len(overrides))
+	for key, value := range scenario.Parameters {
+		jobParams[key] = value
+	}
+	for key, value := range overrides {
+		jobParams[key] = value
 	}
 
-	// Generate the JSON report
-	statsJSON, err := json.Marshal(ss.jobTesterMetrics)
+	rendered, err := parameters.NewRenderer().Render(jobParams, parameters.Context{
+		Region:       ss.config.Region,
+		Orchestrator: orchEthAddr,
+		ServiceURI:   orchServiceUri,
+		Pipeline:     pipeline,
+		Model:        model,
+		Timestamp:    timestamp,
+	})
 	if err != nil {
-		log.Println("Error marshalling job stats to JSON:", err)
-		return err
+		return nil, fmt.Errorf("failed to render job parameters for pipeline %s: %w", pipeline, err)
 	}
-	log.Println("Job Stats Report:")
-	log.Println(string(statsJSON))
-	return nil
+	rendered["model_id"] = model
+	return rendered, nil
 }
 
-// SendTestJob sends a test job to the specified orchestrator and pipeline, including the model name and warm status.
-// It updates the job tester metrics and processes the response, handling errors and capturing response data.
-func (ss *EmbeddedWebhookServer) SendTestJob(orchEthAddr, orchServiceUri, pipeline, model string, modelIsWarm bool) error {
+// sendScenarioJob sends a single test job for one scenario of a pipeline.
+// The job is made of one or more samples, as configured on the pipeline, whose results are aggregated
+// into a single stats record. It updates the job tester metrics and processes the responses,
+// handling errors and capturing response data. A job cancelled through the context is counted as a tester error.
+func (ss *EmbeddedWebhookServer) sendScenarioJob(ctx context.Context, orchEthAddr, orchServiceUri, pipeline, model string, modelIsWarm bool, cfgPipeline *config.Pipeline, scenario *config.Scenario) error {
 	// Increment total jobs metric.
 	ss.jobTesterMetrics.IncrementTotalJobs()
 
-	// Find pipeline parameters from the config.
-	cfgPipeline, found := ss.findParametersByPipelineName(pipeline)
-	if !found {
+	sampleSpacing, err := cfgPipeline.SampleSpacing()
+	if err != nil {
 		ss.jobTesterMetrics.IncrementTotalJobsTesterError()
-		return fmt.Errorf("[SendTestJob] pipeline not found in configuration file: %s", pipeline)
+		return fmt.Errorf("[sendScenarioJob] %w", err)
+	}
+	timeout, err := scenario.TimeoutDuration()
+	if err != nil {
+		ss.jobTesterMetrics.IncrementTotalJobsTesterError()
+		return fmt.Errorf("[sendScenarioJob] %w", err)
 	}
 
-	// Copy pipeline parameters and add the model ID.
-	copiedParams := make(map[string]interface{})
-	for key, value := range cfgPipeline.Parameters {
-		copiedParams[key] = value
+	// Build the job parameters for this orchestrator and model.
+	timestamp := time.Now().Unix()
+	copiedParams, err := ss.jobParameters(cfgPipeline, scenario, orchEthAddr, orchServiceUri, pipeline, model, timestamp)
+	if err != nil {
+		ss.jobTesterMetrics.IncrementTotalJobsTesterError()
+		return fmt.Errorf("[sendScenarioJob] %w", err)
+	}
+	if _, set := copiedParams["stream"]; cfgPipeline.StreamResponse && !set {
+		copiedParams["stream"] = true
 	}
-	copiedParams["model_id"] = model
 
 	// Marshal the parameters into JSON format.
 	input, err := json.Marshal(copiedParams)
 	if err != nil {
 		ss.jobTesterMetrics.IncrementTotalJobsTesterError()
-		return fmt.Errorf("[SendTestJob] failed to create job parameters for pipeline %s: %w", pipeline, err)
+		return fmt.Errorf("[sendScenarioJob] failed to create job parameters for pipeline %s: %w", pipeline, err)
 	}
 
 	// Initialize stats for the test job.
 	stats := types.Stats{
 		Region:       ss.config.Region,
 		Pipeline:     pipeline,
+		Scenario:     scenario.Name,
 		Model:        model,
 		ModelIsWarm:  modelIsWarm,
 		Orchestrator: orchEthAddr,
-		Timestamp:    time.Now().Unix(),
+		Timestamp:    timestamp,
 		SuccessRate:  0,
 		Errors:       make([]types.Error, 0),
 	}
 	stats.InputParameters = string(input)
 
-	// Send the HTTP request.
+	// Send each sample, keeping the last error body and the last successful body.
+	logger := logging.FromContext(ctx)
 	url := fmt.Sprintf("%s/%s", ss.config.BroadcasterJobEndpoint, cfgPipeline.Uri)
-	var req *http.Request
-	if cfgPipeline.ContentType == "application/json" {
-		req, err = http.NewRequest("POST", url, bytes.NewBuffer(input))
+	logger.Debug("Job request", "url", url, "input", string(input))
+	var errorBody, successBody string
+	for i := 0; i < cfgPipeline.SampleCount(); i++ {
+		if i > 0 && sampleSpacing > 0 {
+			select {
+			case <-ctx.Done():
+			case <-time.After(sampleSpacing):
+			}
+		}
+		if ctx.Err() != nil {
+			break
+		}
+
+		req, err := ss.createJobRequest(url, cfgPipeline, scenario, copiedParams, input)
 		if err != nil {
 			ss.jobTesterMetrics.IncrementTotalJobsTesterError()
-			return fmt.Errorf("[SendTestJob] failed to create new HTTP request: %w", err)
+			return fmt.Errorf("[sendScenarioJob] %w", err)
 		}
-		req.Header.Set("Content-Type", cfgPipeline.ContentType)
-		req.Header.Set("Authorization", "Bearer "+ss.config.BroadcasterRequestToken)
+		req = req.WithContext(ctx)
+
+		sample, body := ss.sendSample(req, sampleOptions{
+			timeout:        timeout,
+			validator:      scenario.Validator,
+			streamResponse: cfgPipeline.StreamResponse,
+			params:         copiedParams,
+		})
+		stats.AddSample(sample)
+		logger.Debug("Job response", "sample", i, "status_code", sample.StatusCode, "success", sample.Success, "round_trip_time", sample.RoundTripTime, "body", body)
+		if sample.Success {
+			successBody = body
+		} else if sample.StatusCode != 0 {
+			errorBody = body
+		}
+	}
+	if ctx.Err() != nil {
+		ss.jobTesterMetrics.IncrementTotalJobsTesterError()
+		return fmt.Errorf("[sendScenarioJob] job cancelled: %w", ctx.Err())
+	}
+	stats.Summarize()
+
+	// Don't blame the orchestrator for a job whose input was rejected; every orchestrator would fail it.
+	if statusCode, rejected := rejectedInput(stats.Samples); rejected {
+		ss.jobTesterMetrics.IncrementTotalJobsTesterError()
+		ss.jobTesterMetrics.RecordRejectedInput(pipeline)
+		logger.Debug("Rejected job input", "status_code", statusCode, "body", errorBody)
+		return fmt.Errorf("[sendScenarioJob] the gateway rejected the job input with status %d", statusCode)
+	}
+
+	// Capture the error response from gateway, or the successful response if necessary.
+	if errorBody != "" {
+		stats.ResponsePayload = errorBody
+	} else if cfgPipeline.CaptureResponse {
+		stats.ResponsePayload = successBody
 	} else {
-		req, err = ss.createMultipartRequest(url, copiedParams, cfgPipeline.Uri)
+		stats.ResponsePayload = "{\"message\":\"(Job Tester) Capture Response Disabled\"}"
+	}
+
+	// Don't attribute the job to the orchestrator if another one served it.
+	if err := ss.verifyOrchestrator(&stats, orchServiceUri); err != nil {
+		ss.jobTesterMetrics.IncrementTotalJobsTesterError()
+		return fmt.Errorf("[sendScenarioJob] %w", err)
+	}
+
+	// Finalize stats and report the result.
+	if stats.SuccessRate == 1 {
+		return ss.handleSuccess(ctx, &stats)
+	}
+	return ss.handleFailure(ctx, &stats)
+}
+
+// createJobRequest builds the HTTP request for a single sample of a test job.
+// JSON pipelines send the marshalled parameters, all others are sent as multipart form data with the scenario assets.
+func (ss *EmbeddedWebhookServer) createJobRequest(url string, cfgPipeline *config.Pipeline, scenario *config.Scenario, params map[string]interface{}, input []byte) (*http.Request, error) {
+	if cfgPipeline.ContentType == "application/json" {
+		req, err := http.NewRequest("POST", url, bytes.NewBuffer(input))
 		if err != nil {
-			ss.jobTesterMetrics.IncrementTotalJobsTesterError()
-			return fmt.Errorf("[SendTestJob] failed to create multipart request: %w", err)
+			return nil, fmt.Errorf("failed to create new HTTP request: %w", err)
+		}
+		req.Header.Set("Content-Type", cfgPipeline.ContentType)
+		req.Header.Set("Authorization", "Bearer "+ss.config.BroadcasterRequestToken)
+		if cfgPipeline.StreamResponse {
+			req.Header.Set("Accept", "text/event-stream")
 		}
+		return req, nil
+	}
+
+	assets := scenario.Assets
+	if len(assets) == 0 {
+		assets = defaultAssets(cfgPipeline.Uri)
+	}
+	req, err := ss.createMultipartRequest(url, params, assets)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create multipart request: %w", err)
+	}
+	if cfgPipeline.StreamResponse {
+		req.Header.Set("Accept", "text/event-stream")
+	}
+	return req, nil
+}
+
+// sampleOptions controls how a single sample is sent and how its response is checked.
+type sampleOptions struct {
+	timeout        time.Duration          // Bounds the request when positive.
+	validator      *config.Validator      // Checks successful responses.
+	streamResponse bool                   // Parses server-sent event responses as a streamed LLM reply.
+	params         map[string]interface{} // The job parameters, compared with the inspected media.
+}
+
+// sendSample sends a single request and measures its round-trip time, including reading the body.
+// Successful responses are checked with the validator, and their media inspected when required.
+// It returns the resulting sample along with the response body.
+func (ss *EmbeddedWebhookServer) sendSample(req *http.Request, opts sampleOptions) (types.Sample, string) {
+	sample := types.Sample{Timestamp: time.Now().Unix()}
+
+	if opts.timeout > 0 {
+		ctx, cancel := context.WithTimeout(req.Context(), opts.timeout)
+		defer cancel()
+		req = req.WithContext(ctx)
 	}
 
-	// Measure round-trip time.
+	// Measure round-trip time. The request span covers the inference until the response headers arrive,
+	// the response span the reading and checking of the body.
+	jobCtx := req.Context()
+	ctx, span := tracing.Start(jobCtx, "job.request", trace.WithSpanKind(trace.SpanKindClient))
+	req = req.WithContext(ctx)
+	tracing.Inject(ctx, req.Header)
 	startTime := time.Now()
 	res, err := ss.client.Do(req)
-	jobTime := time.Now()
-
-	// Handle request errors.
 	if err != nil {
-		stats.RoundTripTime = jobTime.Sub(startTime).Seconds()
-		return ss.handleRequestError(err, "failed to process the job", &stats)
+		sample.RoundTripTime = time.Since(startTime).Seconds()
+		sample.Error = requestError(err, "failed to process the job")
+		tracing.End(span, err)
+		return sample, ""
 	}
 	defer res.Body.Close()
+	sample.ServedBy = ss.servedByHeader(res.Header)
+	span.SetAttributes(attribute.Int("http.response.status_code", res.StatusCode))
+	span.End()
+
+	_, span = tracing.Start(jobCtx, "job.response")
+	defer func() {
+		span.SetAttributes(attribute.Bool("job.sample.success", sample.Success))
+		span.End()
+	}()
+
+	// Parse streamed LLM responses as they arrive to measure time-to-first-token.
+	sample.StatusCode = res.StatusCode
+	if opts.streamResponse && res.StatusCode >= 200 && res.StatusCode < 300 && isEventStream(res) {
+		body, llmStats, err := readLLMStream(res.Body, startTime)
+		sample.RoundTripTime = time.Since(startTime).Seconds()
+		sample.LLM = llmStats
+		if err != nil {
+			sample.Error = requestError(err, "failed to read response stream")
+			return sample, body
+		}
+		if !llmStats.StreamCompleted {
+			sample.Error = requestError(errors.New("stream ended before completion"), "response stream incomplete")
+			return sample, body
+		}
+		sample.Success = true
+		return sample, body
+	}
+
 	body, err := ioutil.ReadAll(res.Body)
-	readBodyTime := time.Now()
+	sample.RoundTripTime = time.Since(startTime).Seconds()
 	if err != nil {
-		stats.RoundTripTime = readBodyTime.Sub(startTime).Seconds()
-		return ss.handleRequestError(err, "failed to read response body", &stats)
+		sample.Error = requestError(err, "failed to read response body")
+		return sample, ""
 	}
-	stats.RoundTripTime = readBodyTime.Sub(startTime).Seconds()
 
 	// Check status code and handle errors.
 	if res.StatusCode < 200 || res.StatusCode >= 300 {
-		//capture the error response from gateway
-		stats.ResponsePayload = string(body)
-		return ss.handleStatusCodeError(res.StatusCode, string(body), &stats)
+		sample.Error = statusCodeError(res.StatusCode, string(body))
+		return sample, string(body)
 	}
 
-	// Capture response if necessary.
-	if cfgPipeline.CaptureResponse {
-		stats.ResponsePayload = string(body)
-	} else {
-		stats.ResponsePayload = "{\"message\":\"(Job Tester) Capture Response Disabled\"}"
+	// Validate the response body and inspect the generated media.
+	if err := validation.Validate(opts.validator, body); err != nil {
+		sample.Error = requestError(err, "response failed validation")
+		return sample, string(body)
+	}
+	if validation.InspectsMedia(opts.validator) {
+		media, err := validation.NewInspector(ss.client, ss.config.BroadcasterJobEndpoint).Inspect(opts.validator, body, opts.params)
+		sample.Media = media
+		if err != nil {
+			sample.Error = requestError(err, "media output failed inspection")
+			return sample, string(body)
+		}
 	}
 
-	// Finalize stats and report success.
-	return ss.handleSuccess(&stats)
+	sample.Success = true
+	return sample, string(body)
 }
 
-// webServerHandlers sets up the HTTP handlers for the server, including the /orchestrators endpoint.
+// webServerHandlers sets up the HTTP handlers for the server, including the /orchestrators endpoint,
+// the read-only dashboard and, when an admin token is configured, the admin API. The dashboard state shows the
+// orchestrators and results, so it needs the same authentication as the webhook; the page itself holds no data.
 func (ss *EmbeddedWebhookServer) webServerHandlers() *http.ServeMux {
 	mux := http.NewServeMux()
-	mux.HandleFunc("/orchestrators", ss.handleOrchestrators)
+	mux.HandleFunc("/orchestrators", ss.requireWebhookAuth(ss.handleOrchestrators))
+	mux.HandleFunc("/dashboard", ss.handleDashboard)
+	mux.HandleFunc("/dashboard/state", ss.requireWebhookAuth(ss.handleDashboardState))
+	if ss.config.AdminToken != "" {
+		ss.adminHandlers(mux)
+	}
 	return mux
 }
 
 // handleOrchestrators handles HTTP GET requests to the /orchestrators endpoint.
-// It returns a list of orchestrators in JSON format.
+// It returns a list of orchestrators in JSON format. Calls made while a job is in progress are
+// traced as part of the job, since the gateway calls the webhook while processing it.
 func (ss *EmbeddedWebhookServer) handleOrchestrators(w http.ResponseWriter, r *http.Request) {
 	if r.Method != "GET" {
 		w.WriteHeader(http.StatusMethodNotAllowed)
@@ -267,6 +503,14 @@ func (ss *EmbeddedWebhookServer) handleOrchestrators(w http.ResponseWriter, r *h
 	}
 	w.Header().Set("Content-Type", "application/json")
 
+	ss.lock.RLock()
+	jobSpan := ss.jobSpan
+	ss.lock.RUnlock()
+	if jobSpan.IsValid() {
+		_, span := tracing.Start(trace.ContextWithSpanContext(r.Context(), jobSpan), "gateway.webhook", trace.WithSpanKind(trace.SpanKindServer))
+		defer span.End()
+	}
+
 	type orch struct {
 		Address string `json:"address"`
 	}
@@ -274,6 +518,8 @@ func (ss *EmbeddedWebhookServer) handleOrchestrators(w http.ResponseWriter, r *h
 	var orchs []orch
 	orchToTest := ss.GetOrchToTest()
 	if orchToTest == "" {
+		ss.lock.RLock()
+		defer ss.lock.RUnlock()
 		for _, o := range ss.orchestrators {
 			orchs = append(orchs, orch{o.ServiceURI})
 		}
@@ -290,18 +536,22 @@ func (ss *EmbeddedWebhookServer) handleOrchestrators(w http.ResponseWriter, r *h
 	w.Write(res)
 }
 
-// findParametersByPipelineName searches for a pipeline by name in the configuration file.
-func (ss *EmbeddedWebhookServer) findParametersByPipelineName(pipelineName string) (*config.Pipeline, bool) {
-	for _, pipeline := range ss.config.Pipelines {
-		if pipeline.Name == pipelineName {
-			return &pipeline, true
-		}
+// defaultAssets returns the test assets sent with multipart pipelines that don't configure any,
+// keyed by form field name.
+func defaultAssets(uri string) map[string]string {
+	switch uri {
+	case "audio-to-text":
+		return map[string]string{"audio": "test-assets/test-audio.mp4"}
+	case "upscale":
+		return map[string]string{"image": "test-assets/test-upscale.jpg"}
+	default:
+		return map[string]string{"image": "test-assets/test-image.png"}
 	}
-	return nil, false
 }
 
 // createMultipartRequest creates a new multipart/form-data request for pipelines that require file uploads.
-func (ss *EmbeddedWebhookServer) createMultipartRequest(url string, params map[string]interface{}, uri string) (*http.Request, error) {
+// Each asset is attached as a form file under its field name.
+func (ss *EmbeddedWebhookServer) createMultipartRequest(url string, params map[string]interface{}, assets map[string]string) (*http.Request, error) {
 	// Prepare the multipart form data.
 	var buffer bytes.Buffer
 	writer := multipart.NewWriter(&buffer)
@@ -311,83 +561,165 @@ func (ss *EmbeddedWebhookServer) createMultipartRequest(url string, params map[s
 		_ = writer.WriteField(key, fmt.Sprintf("%v", value))
 	}
 
-	// Add the file based on the URI.
-	var testFileName, fileFieldName string
-	switch uri {
-	case "audio-to-text":
-		testFileName = "test-assets/test-audio.mp4"
-		fileFieldName = "audio"
-	case "upscale":
-		testFileName = "test-assets/test-upscale.jpg"
-		fileFieldName = "image"
-	default:
-		testFileName = "test-assets/test-image.png"
-		fileFieldName = "image"
+	// Add the asset files.
+	for fileFieldName, testFileName := range assets {
+		if err := addFormFile(writer, fileFieldName, testFileName); err != nil {
+			return nil, err
+		}
 	}
 
-	file, err := os.Open(testFileName)
+	// Close the multipart writer to set the terminating boundary.
+	err := writer.Close()
 	if err != nil {
-		return nil, fmt.Errorf("Error opening file: %v", err)
+		return nil, fmt.Errorf("Error closing writer: %v", err)
 	}
-	defer file.Close()
 
-	part, err := writer.CreateFormFile(fileFieldName, file.Name())
+	req, err := http.NewRequest("POST", url, &buffer)
 	if err != nil {
-		return nil, fmt.Errorf("Error creating form file: %v", err)
+		return nil, fmt.Errorf("[createMultipartRequest] failed to get response for POST test: %v", err)
 	}
 
-	_, err = io.Copy(part, file)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	req.Header.Set("Authorization", "Bearer "+ss.config.BroadcasterRequestToken)
+	return req, nil
+}
+
+// addFormFile copies the file at path into a new form file field of the multipart writer.
+func addFormFile(writer *multipart.Writer, fieldName, path string) error {
+	file, err := os.Open(path)
 	if err != nil {
-		return nil, fmt.Errorf("Error copying image to form file: %v", err)
+		return fmt.Errorf("Error opening file: %v", err)
 	}
+	defer file.Close()
 
-	// Close the multipart writer to set the terminating boundary.
-	err = writer.Close()
+	part, err := writer.CreateFormFile(fieldName, file.Name())
 	if err != nil {
-		return nil, fmt.Errorf("Error closing writer: %v", err)
+		return fmt.Errorf("Error creating form file: %v", err)
 	}
 
-	req, err := http.NewRequest("POST", url, &buffer)
+	_, err = io.Copy(part, file)
 	if err != nil {
-		return nil, fmt.Errorf("[createMultipartRequest] failed to get response for POST test: %v", err)
+		return fmt.Errorf("Error copying %s to form file: %v", path, err)
 	}
-
-	req.Header.Set("Content-Type", writer.FormDataContentType())
-	req.Header.Set("Authorization", "Bearer "+ss.config.BroadcasterRequestToken)
-	return req, nil
+	return nil
 }
 
-// handleRequestError handles errors that occur while processing a request.
-// It updates job stats and posts the error data to the Leaderboard API.
-func (ss *EmbeddedWebhookServer) handleRequestError(err error, message string, stats *types.Stats) error {
-	newError := types.Error{
+// requestError builds the job error for a request that could not be completed.
+func requestError(err error, message string) *types.Error {
+	return &types.Error{
 		ErrorCode: fmt.Errorf("%w", err).Error(),
 		Message:   message,
 		Count:     1,
 	}
-	stats.Errors = append(stats.Errors, newError)
-	ss.jobTesterMetrics.IncrementTotalJobsFailed()
-	return ss.livepeerService.PostStats(stats)
 }
 
-// handleSuccess handles successful completion of a test job by updating job stats and posting them to the Leaderboard API.
-func (ss *EmbeddedWebhookServer) handleSuccess(stats *types.Stats) error {
-	stats.SuccessRate = 1
-	ss.jobTesterMetrics.IncrementTotalJobsPassed()
-	return ss.livepeerService.PostStats(stats)
-}
-
-// handleStatusCodeError handles errors related to non-2xx status codes in HTTP responses.
-// It updates job stats and posts the error data to the Leaderboard API.
-func (ss *EmbeddedWebhookServer) handleStatusCodeError(statusCode int, message string, stats *types.Stats) error {
-	newError := types.Error{
+// statusCodeError builds the job error for a response with a non-2xx status code.
+func statusCodeError(statusCode int, message string) *types.Error {
+	return &types.Error{
 		ErrorCode: strconv.Itoa(statusCode),
 		Message:   message,
 		Count:     1,
 	}
-	stats.Errors = append(stats.Errors, newError)
+}
+
+// inputErrorStatusCodes are the status codes of a request rejected for its content: a malformed request,
+// a body too large, an unsupported content type or parameters failing the request schema.
+var inputErrorStatusCodes = map[int]bool{
+	http.StatusBadRequest:            true,
+	http.StatusRequestEntityTooLarge: true,
+	http.StatusUnsupportedMediaType:  true,
+	http.StatusUnprocessableEntity:   true,
+}
+
+// rejectedInput reports whether the job's input was rejected, with the status code: every sample was
+// rejected for its content with a runner validation error, so the job failed because of the tester's parameters.
+// Any other failure, e.g. a 400 from an orchestrator without the runner's error body, is the orchestrator's.
+func rejectedInput(samples []types.Sample) (int, bool) {
+	statusCode := 0
+	for _, sample := range samples {
+		if sample.Success || !inputErrorStatusCodes[sample.StatusCode] || sample.Error == nil || !isValidationError(sample.Error.Message) {
+			return 0, false
+		}
+		statusCode = sample.StatusCode
+	}
+	return statusCode, statusCode != 0
+}
+
+// isValidationError reports whether a response body is a validation error of the AI runner,
+// a JSON object with a non-empty "detail" list of the rejected fields.
+func isValidationError(body string) bool {
+	var validationError struct {
+		Detail []json.RawMessage `json:"detail"`
+	}
+	if err := json.Unmarshal([]byte(body), &validationError); err != nil {
+		return false
+	}
+	return len(validationError.Detail) > 0
+}
+
+// handleSuccess handles successful completion of a test job by updating job stats and posting them to the Leaderboard API.
+func (ss *EmbeddedWebhookServer) handleSuccess(ctx context.Context, stats *types.Stats) error {
+	logging.FromContext(ctx).Info("Test job passed", "success_rate", stats.SuccessRate, "round_trip_time", stats.RoundTripTime)
+	ss.jobTesterMetrics.IncrementTotalJobsPassed()
+	ss.jobTesterMetrics.RecordJob(*stats)
+	ss.results.Add(*stats.Summary())
+	return ss.postStats(ctx, stats)
+}
+
+// handleFailure handles a test job where one or more samples failed.
+// It updates job stats and posts the error data to the Leaderboard API. The error messages hold the
+// gateway's response bodies, so only their codes are logged as a warning and the messages at debug level.
+func (ss *EmbeddedWebhookServer) handleFailure(ctx context.Context, stats *types.Stats) error {
+	logger := logging.FromContext(ctx)
+	errorCodes := make([]string, len(stats.Errors))
+	for i, jobError := range stats.Errors {
+		errorCodes[i] = jobError.ErrorCode
+	}
+	logger.Warn("Test job failed", "success_rate", stats.SuccessRate, "round_trip_time", stats.RoundTripTime, "error_codes", errorCodes)
+	logger.Debug("Test job errors", "errors", stats.Errors)
 	ss.jobTesterMetrics.IncrementTotalJobsFailed()
-	return ss.livepeerService.PostStats(stats)
+	ss.jobTesterMetrics.RecordJob(*stats)
+	ss.results.Add(*stats.Summary())
+	return ss.postStats(ctx, stats)
+}
+
+// postStats posts the summary of the job stats to the Leaderboard API.
+func (ss *EmbeddedWebhookServer) postStats(ctx context.Context, stats *types.Stats) error {
+	_, span := tracing.Start(ctx, "stats.post", trace.WithAttributes(attribute.Float64("job.success_rate", stats.SuccessRate)))
+	summary := stats.Summary()
+	err := ss.livepeerService.PostStats(summary)
+	tracing.End(span, err)
+	if err != nil {
+		return err
+	}
+
+	// Log the posted stats with the job's logger. The posted body is only logged at debug level.
+	// Batched stats are only queued here; their delivery is logged when the batch is sent.
+	logger := logging.FromContext(ctx)
+	if logger.Enabled(ctx, slog.LevelDebug) {
+		message := "Posted stats"
+		if _, batched := ss.livepeerService.(services.StatsFlusher); batched {
+			message = "Queued stats"
+		}
+		body, _ := json.Marshal(summary)
+		logger.Debug(message, "region", stats.Region, "orchestrator", stats.Orchestrator, "pipeline", stats.Pipeline, "model", stats.Model, "success_rate", stats.SuccessRate, "round_trip_time", stats.RoundTripTime, "body", string(body))
+	}
+	return nil
+}
+
+// FlushStats posts the stats the Livepeer service holds back for a batch, if it batches them.
+// It is called at the end of every round, and before the tester exits.
+func (ss *EmbeddedWebhookServer) FlushStats(ctx context.Context) {
+	flusher, ok := ss.livepeerService.(services.StatsFlusher)
+	if !ok {
+		return
+	}
+	_, span := tracing.Start(ctx, "stats.flush")
+	err := flusher.FlushStats()
+	tracing.End(span, err)
+	if err != nil {
+		logging.FromContext(ctx).Error("Failed to post batched stats", "err", err)
+	}
 }
 
 // SetOrchToTest sets the orchestrator currently being tested.
@@ -397,6 +729,13 @@ func (ss *EmbeddedWebhookServer) SetOrchToTest(orchServiceUri string) {
 	ss.orchToTest = orchServiceUri
 }
 
+// setJobSpan sets the span of the job in progress.
+func (ss *EmbeddedWebhookServer) setJobSpan(span trace.SpanContext) {
+	ss.lock.Lock()
+	defer ss.lock.Unlock()
+	ss.jobSpan = span
+}
+
 // GetOrchToTest retrieves the currently selected orchestrator for testing.
 func (ss *EmbeddedWebhookServer) GetOrchToTest() string {
 	ss.lock.RLock()
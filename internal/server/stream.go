@@ -0,0 +1,214 @@
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"livepeer-job-tester/internal/config"
+	"livepeer-job-tester/internal/logging"
+	"livepeer-job-tester/internal/stream"
+	"livepeer-job-tester/internal/tracing"
+	"livepeer-job-tester/internal/types"
+	"os"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// sendStreamJob tests a live video pipeline for one scenario. It opens a streaming session through
+// the gateway, pushes the input video in real time, reads the processed video back and tears the
+// session down. Time-to-first-frame, output frame rate and dropped frames are stored in the job stats.
+// A job cancelled through the context is counted as a tester error.
+func (ss *EmbeddedWebhookServer) sendStreamJob(parent context.Context, orchEthAddr, orchServiceUri, pipeline, model string, modelIsWarm bool, cfgPipeline *config.Pipeline, scenario *config.Scenario) error {
+	// Increment total jobs metric.
+	ss.jobTesterMetrics.IncrementTotalJobs()
+
+	settings, err := cfgPipeline.StreamSettings()
+	if err != nil {
+		ss.jobTesterMetrics.IncrementTotalJobsTesterError()
+		return fmt.Errorf("[sendStreamJob] %w", err)
+	}
+	timeout, err := scenario.TimeoutDuration()
+	if err != nil {
+		ss.jobTesterMetrics.IncrementTotalJobsTesterError()
+		return fmt.Errorf("[sendStreamJob] %w", err)
+	}
+	if timeout == 0 {
+		timeout = settings.FirstFrameTimeout + settings.Duration + settings.IdleTimeout + 30*time.Second
+	}
+	ctx, cancel := context.WithTimeout(parent, timeout)
+	defer cancel()
+
+	// Build the job parameters for this orchestrator and model.
+	timestamp := time.Now().Unix()
+	params, err := ss.jobParameters(cfgPipeline, scenario, orchEthAddr, orchServiceUri, pipeline, model, timestamp)
+	if err != nil {
+		ss.jobTesterMetrics.IncrementTotalJobsTesterError()
+		return fmt.Errorf("[sendStreamJob] %w", err)
+	}
+	input, err := json.Marshal(params)
+	if err != nil {
+		ss.jobTesterMetrics.IncrementTotalJobsTesterError()
+		return fmt.Errorf("[sendStreamJob] failed to create job parameters for pipeline %s: %w", pipeline, err)
+	}
+	startBody, err := json.Marshal(map[string]interface{}{"model_id": model, "params": params})
+	if err != nil {
+		ss.jobTesterMetrics.IncrementTotalJobsTesterError()
+		return fmt.Errorf("[sendStreamJob] failed to create start request for pipeline %s: %w", pipeline, err)
+	}
+	logger := logging.FromContext(parent)
+	logger.Debug("Stream start request", "input", string(startBody))
+
+	// Load or synthesize the input video and split it into segments.
+	segments, err := loadStreamSegments(ctx, settings)
+	if err != nil {
+		ss.jobTesterMetrics.IncrementTotalJobsTesterError()
+		return fmt.Errorf("[sendStreamJob] %w", err)
+	}
+
+	// Initialize stats for the test job.
+	stats := types.Stats{
+		Region:          ss.config.Region,
+		Pipeline:        pipeline,
+		Scenario:        scenario.Name,
+		Model:           model,
+		ModelIsWarm:     modelIsWarm,
+		Orchestrator:    orchEthAddr,
+		Timestamp:       timestamp,
+		SuccessRate:     0,
+		Errors:          make([]types.Error, 0),
+		InputParameters: string(input),
+		ResponsePayload: "{\"message\":\"(Job Tester) Capture Response Disabled\"}",
+	}
+
+	streamName, err := newStreamName()
+	if err != nil {
+		ss.jobTesterMetrics.IncrementTotalJobsTesterError()
+		return fmt.Errorf("[sendStreamJob] %w", err)
+	}
+	startURL := fmt.Sprintf("%s/%s", ss.config.BroadcasterJobEndpoint, strings.ReplaceAll(settings.StartPath, "{stream}", streamName))
+	streamURL := fmt.Sprintf("%s/%s", ss.config.BroadcasterJobEndpoint, strings.ReplaceAll(settings.StreamPath, "{stream}", streamName))
+
+	// Open the session, stream the video and tear the session down.
+	sample := types.Sample{Timestamp: time.Now().Unix()}
+	startTime := time.Now()
+	startCtx, span := tracing.Start(ctx, "stream.start", trace.WithSpanKind(trace.SpanKindClient))
+	session, err := stream.Start(startCtx, ss.client, startURL, streamURL, ss.config.BroadcasterRequestToken, startBody)
+	tracing.End(span, err)
+	if err != nil && parent.Err() != nil {
+		ss.jobTesterMetrics.IncrementTotalJobsTesterError()
+		return fmt.Errorf("[sendStreamJob] job cancelled: %w", parent.Err())
+	}
+	if err != nil {
+		sample.RoundTripTime = time.Since(startTime).Seconds()
+		if statusErr, ok := err.(*stream.StatusError); ok {
+			sample.StatusCode = statusErr.StatusCode
+			sample.Error = statusCodeError(statusErr.StatusCode, statusErr.Body)
+			stats.ResponsePayload = statusErr.Body
+		} else {
+			sample.Error = requestError(err, "failed to start the stream session")
+		}
+		stats.AddSample(sample)
+		stats.Summarize()
+		return ss.handleFailure(parent, &stats)
+	}
+
+	sample.StatusCode = session.StatusCode
+	sample.ServedBy = ss.servedByHeader(session.Header)
+	logger.Debug("Stream session started", "stream", streamName, "publish_url", session.PublishURL, "subscribe_url", session.SubscribeURL)
+	runCtx, span := tracing.Start(ctx, "stream.run")
+	result, runErr := session.Run(runCtx, segments, stream.Options{
+		SegmentDuration:   settings.SegmentDuration,
+		FirstFrameTimeout: settings.FirstFrameTimeout,
+		IdleTimeout:       settings.IdleTimeout,
+	})
+	span.SetAttributes(
+		attribute.Float64("stream.time_to_first_frame", result.TimeToFirstFrame.Seconds()),
+		attribute.Int("stream.frames_sent", result.FramesSent),
+		attribute.Int("stream.frames_received", result.FramesReceived),
+	)
+	tracing.End(span, runErr)
+
+	// The session is stopped even when the job was cancelled, so it's not bound by the job context.
+	stopCtx, stopCancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer stopCancel()
+	stopCtx, span = tracing.Start(trace.ContextWithSpanContext(stopCtx, trace.SpanContextFromContext(ctx)), "stream.stop", trace.WithSpanKind(trace.SpanKindClient))
+	err = session.Stop(stopCtx)
+	tracing.End(span, err)
+	if err != nil {
+		stats.AddError(*requestError(err, "failed to stop the stream session"))
+	}
+	if parent.Err() != nil {
+		ss.jobTesterMetrics.IncrementTotalJobsTesterError()
+		return fmt.Errorf("[sendStreamJob] job cancelled: %w", parent.Err())
+	}
+
+	stats.Stream = &types.StreamStats{
+		TimeToFirstFrame: result.TimeToFirstFrame.Seconds(),
+		OutputFPS:        result.OutputFPS,
+		FramesSent:       result.FramesSent,
+		FramesReceived:   result.FramesReceived,
+		DroppedFrames:    result.DroppedFrames(),
+		SegmentsSent:     result.SegmentsSent,
+		SegmentsReceived: result.SegmentsReceived,
+	}
+
+	// The job round-trip time is the time to the first output frame.
+	if runErr != nil {
+		sample.RoundTripTime = time.Since(startTime).Seconds()
+		sample.Error = requestError(runErr, "failed to stream the video")
+	} else {
+		sample.RoundTripTime = result.TimeToFirstFrame.Seconds()
+		sample.Success = true
+	}
+	stats.AddSample(sample)
+	stats.Summarize()
+
+	// Don't attribute the job to the orchestrator if another one served it.
+	if err := ss.verifyOrchestrator(&stats, orchServiceUri); err != nil {
+		ss.jobTesterMetrics.IncrementTotalJobsTesterError()
+		return fmt.Errorf("[sendStreamJob] %w", err)
+	}
+
+	if stats.SuccessRate == 1 {
+		return ss.handleSuccess(parent, &stats)
+	}
+	return ss.handleFailure(parent, &stats)
+}
+
+// loadStreamSegments reads the MPEG-TS input asset, or synthesizes a test pattern when none is
+// configured, and splits it into segments for publishing.
+func loadStreamSegments(ctx context.Context, settings *config.StreamSettings) ([]stream.Segment, error) {
+	var data []byte
+	var err error
+	if settings.Asset != "" {
+		data, err = os.ReadFile(settings.Asset)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read stream asset: %w", err)
+		}
+	} else {
+		data, err = stream.Synthesize(ctx, settings.Duration, settings.Width, settings.Height, settings.FPS)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	segments := stream.SplitTS(data, settings.SegmentDuration)
+	if len(segments) == 0 {
+		return nil, fmt.Errorf("stream input contains no video frames")
+	}
+	return segments, nil
+}
+
+// newStreamName returns a unique stream name for a test session.
+func newStreamName() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate stream name: %w", err)
+	}
+	return "jobtester-" + hex.EncodeToString(b), nil
+}
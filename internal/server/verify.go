@@ -0,0 +1,115 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"livepeer-job-tester/internal/types"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// servedByHeader returns the orchestrator named in the configured verification header of a response, if any.
+func (ss *EmbeddedWebhookServer) servedByHeader(header http.Header) string {
+	verification := ss.config.OrchestratorVerification
+	if verification == nil || verification.Header == "" {
+		return ""
+	}
+	return strings.TrimSpace(header.Get(verification.Header))
+}
+
+// verifyOrchestrator checks that the job was served by the pinned orchestrator, using the orchestrators
+// reported by the samples' response headers and the gateway debug endpoint. It records the serving
+// orchestrator in the stats and returns an error when a different orchestrator served the job, or when
+// verification is required but no orchestrator was reported. Jobs without any response, e.g. when the
+// gateway could not be reached, are not required to be verified.
+func (ss *EmbeddedWebhookServer) verifyOrchestrator(stats *types.Stats, orchServiceUri string) error {
+	verification := ss.config.OrchestratorVerification
+	if verification == nil {
+		return nil
+	}
+
+	var served []string
+	required := false
+	for _, sample := range stats.Samples {
+		if sample.StatusCode != 0 {
+			required = verification.Required
+		}
+		if sample.ServedBy != "" {
+			served = append(served, sample.ServedBy)
+		}
+	}
+	if verification.DebugEndpoint != "" {
+		orch, err := ss.fetchServingOrchestrator(stats.Pipeline, stats.Model)
+		if err != nil && required {
+			return fmt.Errorf("[verifyOrchestrator] failed to query gateway debug endpoint: %w", err)
+		}
+		if orch != "" {
+			served = append(served, orch)
+		}
+	}
+
+	if len(served) == 0 {
+		if required {
+			return fmt.Errorf("[verifyOrchestrator] could not verify that orchestrator %s served the job", stats.Orchestrator)
+		}
+		return nil
+	}
+	for _, orch := range served {
+		if !orchestratorMatches(orch, stats.Orchestrator, orchServiceUri) {
+			return fmt.Errorf("[verifyOrchestrator] job for orchestrator %s (%s) was served by %s", stats.Orchestrator, orchServiceUri, orch)
+		}
+	}
+	stats.ServedBy = served[len(served)-1]
+	return nil
+}
+
+// fetchServingOrchestrator asks the gateway debug endpoint which orchestrator served the last job of the pipeline and model.
+func (ss *EmbeddedWebhookServer) fetchServingOrchestrator(pipeline, model string) (string, error) {
+	endpoint := strings.NewReplacer(
+		"{pipeline}", url.QueryEscape(pipeline),
+		"{model}", url.QueryEscape(model),
+	).Replace(ss.config.OrchestratorVerification.DebugEndpoint)
+
+	res, err := ss.client.Get(endpoint)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("response contained a non-200 status code: %d", res.StatusCode)
+	}
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return "", err
+	}
+
+	var debug struct {
+		Orchestrator string `json:"orchestrator"`
+	}
+	if err := json.Unmarshal(body, &debug); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(debug.Orchestrator), nil
+}
+
+// orchestratorMatches reports whether a reported orchestrator is the expected one. The reported value
+// may be the orchestrator's eth address, its service URI, or the host:port of its service URI.
+func orchestratorMatches(reported, ethAddress, serviceURI string) bool {
+	reported = strings.TrimSuffix(strings.ToLower(reported), "/")
+	if reported == strings.ToLower(ethAddress) || reported == strings.TrimSuffix(strings.ToLower(serviceURI), "/") {
+		return true
+	}
+	expected, err := url.Parse(serviceURI)
+	if err != nil {
+		return false
+	}
+	if strings.EqualFold(reported, expected.Host) {
+		return true
+	}
+	if actual, err := url.Parse(reported); err == nil && actual.Host != "" {
+		return strings.EqualFold(actual.Host, expected.Host)
+	}
+	return false
+}
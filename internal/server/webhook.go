@@ -0,0 +1,68 @@
+package server
+
+import (
+	"crypto/subtle"
+	"log/slog"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// requireWebhookAuth wraps the webhook handler with the configured checks: the shared secret query
+// parameter, the bearer token and the IP allowlist. Rejected requests are logged without their credentials.
+func (ss *EmbeddedWebhookServer) requireWebhookAuth(next http.HandlerFunc) http.HandlerFunc {
+	webhook := ss.config.Webhook
+	if webhook == nil || (webhook.Secret == "" && webhook.Token == "" && len(webhook.AllowedIPs) == 0) {
+		return next
+	}
+	// The networks were checked when the configuration was loaded.
+	networks, _ := webhook.AllowedNetworks()
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		reject := func(statusCode int, reason string) {
+			slog.Warn("Rejected webhook request", "remote_addr", r.RemoteAddr, "path", r.URL.Path, "reason", reason)
+			w.WriteHeader(statusCode)
+		}
+
+		if len(networks) > 0 && !ipAllowed(r.RemoteAddr, networks) {
+			reject(http.StatusForbidden, "client IP not allowed")
+			return
+		}
+		if webhook.Secret != "" && !secretEqual(r.URL.Query().Get("secret"), webhook.Secret) {
+			reject(http.StatusUnauthorized, "invalid shared secret")
+			return
+		}
+		if webhook.Token != "" {
+			token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !ok || !secretEqual(token, webhook.Token) {
+				w.Header().Set("WWW-Authenticate", "Bearer")
+				reject(http.StatusUnauthorized, "invalid bearer token")
+				return
+			}
+		}
+		next(w, r)
+	}
+}
+
+// ipAllowed reports whether the IP of the remote address is in one of the networks.
+func ipAllowed(remoteAddr string, networks []*net.IPNet) bool {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		host = remoteAddr
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+	for _, network := range networks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// secretEqual compares a credential with the expected secret in constant time.
+func secretEqual(actual, expected string) bool {
+	return subtle.ConstantTimeCompare([]byte(actual), []byte(expected)) == 1
+}
@@ -0,0 +1,207 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"livepeer-job-tester/internal/config"
+	"livepeer-job-tester/internal/types"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// StatsFlusher is implemented by a LivepeerService that posts stats in the background.
+// FlushStats waits for a send in progress, then posts the pending stats and waits for them to be sent.
+type StatsFlusher interface {
+	FlushStats() error
+}
+
+// BatchingLivepeerService is a LivepeerService that groups the stats posted to the Leaderboard API
+// and sends them in batches to the bulk endpoint of config.MetricsBatch. A batch is sent once it holds
+// the maximum number of records, after the maximum wait, or when FlushStats is called, e.g. at the end of a round.
+// Records of a failed batch are queued again and dropped after maxAttempts; pending records are lost on exit.
+// When the server doesn't have the bulk endpoint, the records are posted one by one from then on.
+type BatchingLivepeerService struct {
+	*HTTPLivepeerService
+
+	endpoint string        // Bulk endpoint receiving a JSON array of stats.
+	maxSize  int           // Number of records sending a batch.
+	maxWait  time.Duration // Longest wait of a record before its batch is sent.
+
+	flushLock   sync.Mutex // Held while a batch is sent, so FlushStats waits for a send in progress.
+	lock        sync.Mutex
+	pending     []pendingStats // Records waiting to be sent.
+	timer       *time.Timer    // Sends the pending records after maxWait, while there are any.
+	singlePosts bool           // Set once the server turned out not to have the bulk endpoint.
+}
+
+// maxAttempts is the number of batches a record is sent in before it is dropped.
+const maxAttempts = 3
+
+// pendingStats is a record waiting to be sent, with the number of failed attempts to send it.
+type pendingStats struct {
+	stats    *types.Stats
+	attempts int
+}
+
+// NewBatchingLivepeerService creates a BatchingLivepeerService with the given HTTP client and config,
+// which must have MetricsBatch set.
+func NewBatchingLivepeerService(client *http.Client, config *config.Config) (*BatchingLivepeerService, error) {
+	batch := config.MetricsBatch
+	if batch == nil {
+		return nil, errors.New("[NewBatchingLivepeerService] metricsBatch is not configured")
+	}
+	maxWait, err := batch.WaitDuration()
+	if err != nil {
+		return nil, fmt.Errorf("[NewBatchingLivepeerService] %w", err)
+	}
+	return &BatchingLivepeerService{
+		HTTPLivepeerService: NewHTTPLivepeerService(client, config),
+		endpoint:            batch.Endpoint,
+		maxSize:             batch.BatchSize(),
+		maxWait:             maxWait,
+	}, nil
+}
+
+// PostStats adds the stats to the pending batch, and sends the batch when the stats fill it.
+// It always returns nil: the batch belongs to no single job, so a failed batch is logged instead.
+func (s *BatchingLivepeerService) PostStats(stats *types.Stats) error {
+	s.lock.Lock()
+	s.pending = append(s.pending, pendingStats{stats: stats})
+	full := len(s.pending) >= s.maxSize
+	if !full && s.timer == nil {
+		s.timer = time.AfterFunc(s.maxWait, s.flushAfterWait)
+	}
+	s.lock.Unlock()
+	if full {
+		s.flushAfterWait()
+	}
+	return nil
+}
+
+// FlushStats sends the pending stats, after any batch being sent by another goroutine.
+// The records of a failed batch are queued again, see flush.
+func (s *BatchingLivepeerService) FlushStats() error {
+	return s.flush()
+}
+
+// flushAfterWait sends the pending stats when the batch is full or waited maxWait, logging a failed batch.
+func (s *BatchingLivepeerService) flushAfterWait() {
+	if err := s.flush(); err != nil {
+		slog.Error("Failed to post batched stats", "endpoint", s.endpoint, "err", err)
+	}
+}
+
+// flush sends the pending stats and queues the records that could not be sent again,
+// unless they were already sent maxAttempts times. Flushes run one at a time.
+func (s *BatchingLivepeerService) flush() error {
+	s.flushLock.Lock()
+	defer s.flushLock.Unlock()
+	s.lock.Lock()
+	batch := s.takePending()
+	s.lock.Unlock()
+	failed, err := s.send(batch)
+	if err == nil {
+		return nil
+	}
+
+	var retry []pendingStats
+	dropped := 0
+	for _, record := range failed {
+		record.attempts++
+		if record.attempts < maxAttempts {
+			retry = append(retry, record)
+		} else {
+			dropped++
+		}
+	}
+	if len(retry) > 0 {
+		s.lock.Lock()
+		s.pending = append(retry, s.pending...)
+		if s.timer == nil {
+			s.timer = time.AfterFunc(s.maxWait, s.flushAfterWait)
+		}
+		s.lock.Unlock()
+	}
+	return fmt.Errorf("[BatchingLivepeerService::flush] %d stats queued again, %d dropped after %d attempts: %w", len(retry), dropped, maxAttempts, err)
+}
+
+// takePending returns the pending stats and stops the timer. The caller must hold the lock.
+func (s *BatchingLivepeerService) takePending() []pendingStats {
+	batch := s.pending
+	s.pending = nil
+	if s.timer != nil {
+		s.timer.Stop()
+		s.timer = nil
+	}
+	return batch
+}
+
+// send posts the batch to the bulk endpoint, or each of its stats to the single post endpoint
+// when the server doesn't have the bulk endpoint. It returns the records that could not be sent.
+func (s *BatchingLivepeerService) send(batch []pendingStats) ([]pendingStats, error) {
+	if len(batch) == 0 {
+		return nil, nil
+	}
+	s.lock.Lock()
+	singlePosts := s.singlePosts
+	s.lock.Unlock()
+	if !singlePosts {
+		err := s.postBatch(batch)
+		if err == nil {
+			return nil, nil
+		}
+		if !errors.Is(err, errBulkUnsupported) {
+			return batch, err
+		}
+		s.lock.Lock()
+		s.singlePosts = true
+		s.lock.Unlock()
+		slog.Warn("Leaderboard API has no bulk endpoint, posting stats one by one", "endpoint", s.endpoint, "err", err)
+	}
+
+	var failed []pendingStats
+	var errs []error
+	for _, record := range batch {
+		stats := record.stats
+		if err := s.HTTPLivepeerService.PostStats(stats); err != nil {
+			failed = append(failed, record)
+			errs = append(errs, fmt.Errorf("orchestrator %s pipeline %s model %s: %w", stats.Orchestrator, stats.Pipeline, stats.Model, err))
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return failed, fmt.Errorf("[BatchingLivepeerService::send] failed to post %d of %d stats: %w", len(errs), len(batch), err)
+	}
+	slog.Debug("Posted stats one by one", "endpoint", s.config.MetricsApiEndpoint, "count", len(batch))
+	return nil, nil
+}
+
+// errBulkUnsupported reports a Leaderboard API server without the bulk endpoint.
+var errBulkUnsupported = errors.New("bulk endpoint not supported")
+
+// postBatch posts the batch as a JSON array, signed like a single post. Status codes of a server
+// without the route (404, 405 and 501) are reported as errBulkUnsupported.
+func (s *BatchingLivepeerService) postBatch(batch []pendingStats) error {
+	records := make([]*types.Stats, len(batch))
+	for i, record := range batch {
+		records[i] = record.stats
+	}
+	input, err := json.Marshal(records)
+	if err != nil {
+		return fmt.Errorf("[BatchingLivepeerService::postBatch] %w", err)
+	}
+	statusCode, err := s.postSigned(s.endpoint, input)
+	if err != nil {
+		return fmt.Errorf("[BatchingLivepeerService::postBatch] failed to post %d stats: %w", len(batch), err)
+	}
+	switch {
+	case statusCode == http.StatusNotFound || statusCode == http.StatusMethodNotAllowed || statusCode == http.StatusNotImplemented:
+		return fmt.Errorf("[BatchingLivepeerService::postBatch] status code %d: %w", statusCode, errBulkUnsupported)
+	case statusCode < 200 || statusCode >= 300:
+		return fmt.Errorf("[BatchingLivepeerService::postBatch] invalid response status code from POST STATS BATCH [%v] for %d stats", statusCode, len(batch))
+	}
+	slog.Debug("Posted stats batch", "endpoint", s.endpoint, "count", len(batch))
+	return nil
+}
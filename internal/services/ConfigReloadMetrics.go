@@ -0,0 +1,52 @@
+package services
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+)
+
+// ConfigReloadMetrics counts the reloads of the configuration file that were applied and rejected,
+// and keeps the outcome of the last one. A rejected reload keeps the last good configuration.
+// A read-write mutex is used to safely handle concurrent updates.
+type ConfigReloadMetrics struct {
+	lock sync.RWMutex // RWMutex ensures safe concurrent access to the fields.
+
+	TotalReloads         int    `json:"total_reloads"`          // Number of reloads applied.
+	TotalReloadsRejected int    `json:"total_reloads_rejected"` // Number of reloads rejected.
+	LastReloadAt         int64  `json:"last_reload_at"`         // Unix time of the last reload, applied or rejected.
+	LastReloadError      string `json:"last_reload_error"`      // Error of the last reload, empty when it was applied.
+}
+
+// NewConfigReloadMetrics initializes and returns a pointer to a new ConfigReloadMetrics instance.
+func NewConfigReloadMetrics() *ConfigReloadMetrics {
+	return &ConfigReloadMetrics{}
+}
+
+// RecordReload counts an applied reload.
+// This method locks the mutex to ensure thread-safe operation.
+func (m *ConfigReloadMetrics) RecordReload() {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.TotalReloads++
+	m.LastReloadAt = time.Now().Unix()
+	m.LastReloadError = ""
+}
+
+// RecordRejectedReload counts a rejected reload and keeps its error.
+// This method locks the mutex to ensure thread-safe operation.
+func (m *ConfigReloadMetrics) RecordRejectedReload(err error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.TotalReloadsRejected++
+	m.LastReloadAt = time.Now().Unix()
+	m.LastReloadError = err.Error()
+}
+
+// MarshalJSON encodes the metrics while holding the read lock.
+func (m *ConfigReloadMetrics) MarshalJSON() ([]byte, error) {
+	type metrics ConfigReloadMetrics // Drops the MarshalJSON method to avoid recursion.
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	return json.Marshal((*metrics)(m))
+}
@@ -1,10 +1,13 @@
 package services
 
-import "sync"
+import (
+	"encoding/json"
+	"livepeer-job-tester/internal/types"
+	"sync"
+)
 
-// JobTesterMetrics stores the metrics related to job testing results.
-// It tracks the total number of jobs, jobs that passed, jobs that failed,
-// jobs with tester errors, and the expected total number of jobs.
+// JobTesterMetrics stores the metrics related to job testing results: the job counters, the full stats
+// of each finished job and the capabilities or inputs that could not be tested.
 // A read-write mutex is used to safely handle concurrent updates.
 type JobTesterMetrics struct {
 	lock sync.RWMutex // RWMutex ensures safe concurrent access to the fields.
@@ -14,12 +17,108 @@ type JobTesterMetrics struct {
 	TotalJobsPassed      int `json:"total_jobs_passed"`       // Number of jobs that passed successfully.
 	TotalJobsFailed      int `json:"total_jobs_failed"`       // Number of jobs that failed.
 	ExpectedTotalJobs    int `json:"expected_total_jobs"`     // The expected number of jobs to process.
+
+	UntestedCapabilities map[string]int `json:"untested_capabilities"` // Orch/model pairs skipped per capability type without pipeline config.
+	RejectedInputs       map[string]int `json:"rejected_inputs"`       // Jobs per pipeline rejected by the gateway as bad input, counted as tester errors.
+
+	Jobs        []types.Stats `json:"jobs"`         // Stats of the last maxRecordedJobs finished jobs, including raw samples.
+	DroppedJobs int           `json:"dropped_jobs"` // Finished jobs dropped from Jobs to stay within maxRecordedJobs.
+
+	orchestrators map[string]OrchestratorSummary // Summaries of every finished job, see Orchestrators.
 }
 
+// maxRecordedJobs bounds the number of job stats kept in a round, since they hold the request and response payloads.
+const maxRecordedJobs = 1000
+
 // NewJobTesterMetrics initializes and returns a pointer to a new JobTesterMetrics instance.
 // The returned instance starts with all metrics initialized to zero.
 func NewJobTesterMetrics() *JobTesterMetrics {
-	return &JobTesterMetrics{}
+	return &JobTesterMetrics{
+		UntestedCapabilities: make(map[string]int),
+		RejectedInputs:       make(map[string]int),
+		orchestrators:        make(map[string]OrchestratorSummary),
+	}
+}
+
+// MetricsSummary is a snapshot of the job counters of JobTesterMetrics, without the job stats.
+type MetricsSummary struct {
+	TotalJobs            int            `json:"total_jobs"`
+	TotalJobsTesterError int            `json:"total_jobs_tester_error"`
+	TotalJobsPassed      int            `json:"total_jobs_passed"`
+	TotalJobsFailed      int            `json:"total_jobs_failed"`
+	ExpectedTotalJobs    int            `json:"expected_total_jobs"`
+	UntestedCapabilities map[string]int `json:"untested_capabilities"`
+	RejectedInputs       map[string]int `json:"rejected_inputs"`
+}
+
+// OrchestratorSummary counts the jobs of an orchestrator that passed, with their mean round-trip time.
+type OrchestratorSummary struct {
+	Passed            int     `json:"passed"`
+	Total             int     `json:"total"`
+	MeanRoundTripTime float64 `json:"mean_round_trip_time"`
+}
+
+// Reset clears all metrics, so they can be reused for a new test round.
+// This method locks the mutex to ensure thread-safe operation.
+func (js *JobTesterMetrics) Reset() {
+	js.lock.Lock()
+	defer js.lock.Unlock()
+	js.TotalJobs = 0
+	js.TotalJobsTesterError = 0
+	js.TotalJobsPassed = 0
+	js.TotalJobsFailed = 0
+	js.ExpectedTotalJobs = 0
+	js.UntestedCapabilities = make(map[string]int)
+	js.RejectedInputs = make(map[string]int)
+	js.Jobs = nil
+	js.DroppedJobs = 0
+	js.orchestrators = make(map[string]OrchestratorSummary)
+}
+
+// Summary returns a snapshot of the job counters.
+// This method locks the mutex to ensure thread-safe operation.
+func (js *JobTesterMetrics) Summary() MetricsSummary {
+	js.lock.RLock()
+	defer js.lock.RUnlock()
+	untested := make(map[string]int, len(js.UntestedCapabilities))
+	for capabilityType, count := range js.UntestedCapabilities {
+		untested[capabilityType] = count
+	}
+	rejected := make(map[string]int, len(js.RejectedInputs))
+	for pipeline, count := range js.RejectedInputs {
+		rejected[pipeline] = count
+	}
+	return MetricsSummary{
+		TotalJobs:            js.TotalJobs,
+		TotalJobsTesterError: js.TotalJobsTesterError,
+		TotalJobsPassed:      js.TotalJobsPassed,
+		TotalJobsFailed:      js.TotalJobsFailed,
+		ExpectedTotalJobs:    js.ExpectedTotalJobs,
+		UntestedCapabilities: untested,
+		RejectedInputs:       rejected,
+	}
+}
+
+// Orchestrators summarizes the finished jobs of each orchestrator, keyed by eth address.
+// A job passed when all of its samples succeeded.
+// This method locks the mutex to ensure thread-safe operation.
+func (js *JobTesterMetrics) Orchestrators() map[string]OrchestratorSummary {
+	js.lock.RLock()
+	defer js.lock.RUnlock()
+	summaries := make(map[string]OrchestratorSummary, len(js.orchestrators))
+	for orchestrator, summary := range js.orchestrators {
+		summaries[orchestrator] = summary
+	}
+	return summaries
+}
+
+// MarshalJSON encodes the metrics while holding the read lock, so a report can be
+// generated while jobs are still running.
+func (js *JobTesterMetrics) MarshalJSON() ([]byte, error) {
+	type metrics JobTesterMetrics // Drops the MarshalJSON method to avoid recursion.
+	js.lock.RLock()
+	defer js.lock.RUnlock()
+	return json.Marshal((*metrics)(js))
 }
 
 // IncrementTotalJobs increments the count of TotalJobs by 1.
@@ -61,3 +160,42 @@ func (js *JobTesterMetrics) IncrementExpectedTotalJobs() {
 	defer js.lock.Unlock()
 	js.ExpectedTotalJobs++
 }
+
+// RecordJob appends the stats of a finished job to Jobs, dropping the oldest job once Jobs holds
+// maxRecordedJobs, and adds the job to the summary of its orchestrator.
+// This method locks the mutex to ensure thread-safe operation.
+func (js *JobTesterMetrics) RecordJob(stats types.Stats) {
+	js.lock.Lock()
+	defer js.lock.Unlock()
+	if len(js.Jobs) >= maxRecordedJobs {
+		js.Jobs = append(js.Jobs[:0], js.Jobs[1:]...)
+		js.DroppedJobs++
+	}
+	js.Jobs = append(js.Jobs, stats)
+
+	summary := js.orchestrators[stats.Orchestrator]
+	summary.MeanRoundTripTime = (summary.MeanRoundTripTime*float64(summary.Total) + stats.RoundTripTime) / float64(summary.Total+1)
+	summary.Total++
+	if stats.SuccessRate == 1 {
+		summary.Passed++
+	}
+	js.orchestrators[stats.Orchestrator] = summary
+}
+
+// RecordUntestedCapability counts an orchestrator model advertised for a capability type
+// that has no pipeline configuration, so it is reported instead of being tested.
+// This method locks the mutex to ensure thread-safe operation.
+func (js *JobTesterMetrics) RecordUntestedCapability(capabilityType string) {
+	js.lock.Lock()
+	defer js.lock.Unlock()
+	js.UntestedCapabilities[capabilityType]++
+}
+
+// RecordRejectedInput counts a job of the pipeline whose input the gateway rejected,
+// so a bad parameter is reported instead of being blamed on the orchestrator.
+// This method locks the mutex to ensure thread-safe operation.
+func (js *JobTesterMetrics) RecordRejectedInput(pipeline string) {
+	js.lock.Lock()
+	defer js.lock.Unlock()
+	js.RejectedInputs[pipeline]++
+}
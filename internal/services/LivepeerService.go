@@ -2,14 +2,13 @@ package services
 
 import (
 	"bytes"
-	"crypto/hmac"
-	"crypto/sha256"
-	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"livepeer-job-tester/internal/config"
+	"livepeer-job-tester/internal/leaderboard"
 	"livepeer-job-tester/internal/types"
 	"net/http"
 )
@@ -108,31 +107,36 @@ func (s *HTTPLivepeerService) PostStats(stats *types.Stats) error {
 		return err
 	}
 
-	// Create a new POST request with the stats data.
-	req, err := http.NewRequest("POST", s.config.MetricsApiEndpoint, bytes.NewBuffer(input))
+	// Post the stats data, signed for the Leaderboard API.
+	statusCode, err := s.postSigned(s.config.MetricsApiEndpoint, input)
 	if err != nil {
 		return err
 	}
 
+	// Check the response status code.
+	if statusCode < 200 || statusCode >= 300 {
+		return errors.New(fmt.Sprintf("invalid response status code from POST STATS [%v]", statusCode))
+	}
+	return nil
+}
+
+// postSigned posts the JSON body to the URL and returns the response status code.
+// The body is signed with an HMAC hash using the metrics secret, sent in the Authorization header.
+func (s *HTTPLivepeerService) postSigned(url string, body []byte) (int, error) {
+	req, err := http.NewRequest("POST", url, bytes.NewBuffer(body))
+	if err != nil {
+		return 0, err
+	}
+
 	// Generate an HMAC hash using the metrics secret and the request body.
-	hash := hmac.New(sha256.New, []byte(s.config.MetricsSecret))
-	hash.Write(input)
-	req.Header.Set("Authorization", hex.EncodeToString(hash.Sum(nil)))
+	req.Header.Set("Authorization", leaderboard.Sign(s.config.MetricsSecret, body))
 	req.Header.Set("Content-Type", "application/json")
 
-	// Send the POST request.
 	res, err := s.client.Do(req)
 	if err != nil {
-		return err
+		return 0, err
 	}
 	defer res.Body.Close()
-
-	// Check the response status code.
-	if res.StatusCode < 200 || res.StatusCode >= 300 {
-		return errors.New(fmt.Sprintf("invalid response status code from POST STATS [%v]", res.StatusCode))
-	}
-
-	// Log the successful posting of stats.
-	fmt.Printf("Posted stats for region=[%s] orchestrator=[%s] pipeline=[%s] model=[%s] success=[%v]  latency=[%v] \n", stats.Region, stats.Orchestrator, stats.Pipeline, stats.Model, stats.SuccessRate, stats.RoundTripTime)
-	return nil
+	io.Copy(io.Discard, res.Body)
+	return res.StatusCode, nil
 }
@@ -0,0 +1,63 @@
+package services
+
+import (
+	"livepeer-job-tester/internal/types"
+	"sync"
+)
+
+// ResultHistory keeps the stats of the most recent jobs across test rounds, up to a fixed limit.
+// The oldest results are dropped first. It also keeps the latest result of every orchestrator, pipeline,
+// model and scenario combination, regardless of the limit. A read-write mutex is used to safely handle concurrent updates.
+type ResultHistory struct {
+	lock    sync.RWMutex           // RWMutex ensures safe concurrent access to the results.
+	limit   int                    // Maximum number of results kept.
+	results []types.Stats          // Results in the order they were recorded.
+	latest  map[string]types.Stats // Latest result per orchestrator, pipeline, model and scenario.
+}
+
+// NewResultHistory initializes and returns a pointer to a new ResultHistory keeping up to limit results.
+func NewResultHistory(limit int) *ResultHistory {
+	return &ResultHistory{limit: limit, latest: make(map[string]types.Stats)}
+}
+
+// Add records the stats of a finished job, dropping the oldest result when the history is full.
+// This method locks the mutex to ensure thread-safe operation.
+func (h *ResultHistory) Add(stats types.Stats) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	h.results = append(h.results, stats)
+	h.latest[stats.Orchestrator+"|"+stats.Pipeline+"|"+stats.Model+"|"+stats.Scenario] = stats
+	if len(h.results) > h.limit {
+		h.results = append([]types.Stats(nil), h.results[len(h.results)-h.limit:]...)
+	}
+}
+
+// Recent returns up to limit results accepted by match, newest first.
+// A nil match accepts every result, and a limit of zero or less returns all matching results.
+// This method locks the mutex to ensure thread-safe operation.
+func (h *ResultHistory) Recent(limit int, match func(types.Stats) bool) []types.Stats {
+	h.lock.RLock()
+	defer h.lock.RUnlock()
+	results := make([]types.Stats, 0)
+	for i := len(h.results) - 1; i >= 0; i-- {
+		if limit > 0 && len(results) == limit {
+			break
+		}
+		if match == nil || match(h.results[i]) {
+			results = append(results, h.results[i])
+		}
+	}
+	return results
+}
+
+// Latest returns the latest result of every orchestrator, pipeline, model and scenario combination.
+// This method locks the mutex to ensure thread-safe operation.
+func (h *ResultHistory) Latest() []types.Stats {
+	h.lock.RLock()
+	defer h.lock.RUnlock()
+	results := make([]types.Stats, 0, len(h.latest))
+	for _, stats := range h.latest {
+		results = append(results, stats)
+	}
+	return results
+}
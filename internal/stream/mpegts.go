@@ -0,0 +1,213 @@
+package stream
+
+import (
+	"time"
+)
+
+const (
+	tsPacketSize = 188
+	tsSyncByte   = 0x47
+	ptsClockRate = 90000
+)
+
+// TSInfo summarizes the video content of an MPEG-TS byte stream.
+type TSInfo struct {
+	Frames   int   // Number of video PES packets, i.e. video frames.
+	FirstPTS int64 // Presentation timestamp of the first video frame, in 90kHz ticks.
+	LastPTS  int64 // Presentation timestamp of the last video frame, in 90kHz ticks.
+	HasPTS   bool  // Whether any video frame carried a presentation timestamp.
+}
+
+// Duration returns the time between the first and last video frame.
+func (i TSInfo) Duration() time.Duration {
+	if !i.HasPTS || i.LastPTS <= i.FirstPTS {
+		return 0
+	}
+	return time.Duration(i.LastPTS-i.FirstPTS) * time.Second / ptsClockRate
+}
+
+// Segment is a chunk of an MPEG-TS stream starting at a video keyframe.
+// Each segment starts with the stream's PAT/PMT so it can be decoded on its own.
+type Segment struct {
+	Data     []byte
+	Frames   int
+	Duration time.Duration
+}
+
+// tsPacket holds the fields of a transport stream packet needed to count frames and split segments.
+type tsPacket struct {
+	raw          []byte
+	pid          uint16
+	payloadStart bool
+	randomAccess bool
+	payload      []byte
+}
+
+// parsePacket decodes the header of a single 188 byte packet. It returns false for invalid packets.
+func parsePacket(raw []byte) (tsPacket, bool) {
+	if len(raw) != tsPacketSize || raw[0] != tsSyncByte {
+		return tsPacket{}, false
+	}
+	p := tsPacket{
+		raw:          raw,
+		pid:          uint16(raw[1]&0x1f)<<8 | uint16(raw[2]),
+		payloadStart: raw[1]&0x40 != 0,
+	}
+
+	adaptation := (raw[3] >> 4) & 0x3
+	offset := 4
+	if adaptation == 2 || adaptation == 3 {
+		length := int(raw[4])
+		if length > 0 {
+			p.randomAccess = raw[5]&0x40 != 0
+		}
+		offset += 1 + length
+	}
+	if (adaptation == 1 || adaptation == 3) && offset < len(raw) {
+		p.payload = raw[offset:]
+	}
+	return p, true
+}
+
+// videoPES reports whether the payload starts a video PES packet and returns its PTS, if present.
+func videoPES(payload []byte) (isVideo bool, pts int64, hasPTS bool) {
+	if len(payload) < 9 || payload[0] != 0 || payload[1] != 0 || payload[2] != 1 {
+		return false, 0, false
+	}
+	streamID := payload[3]
+	if streamID < 0xe0 || streamID > 0xef {
+		return false, 0, false
+	}
+	if payload[7]&0x80 == 0 || len(payload) < 14 {
+		return true, 0, false
+	}
+	b := payload[9:14]
+	pts = int64(b[0]>>1&0x07)<<30 | int64(b[1])<<22 | int64(b[2]>>1)<<15 | int64(b[3])<<7 | int64(b[4]>>1)
+	return true, pts, true
+}
+
+// packets splits a byte stream into valid transport stream packets, skipping trailing partial data.
+func packets(data []byte) []tsPacket {
+	var out []tsPacket
+	for offset := 0; offset+tsPacketSize <= len(data); offset += tsPacketSize {
+		if p, ok := parsePacket(data[offset : offset+tsPacketSize]); ok {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// AnalyzeTS counts the video frames of an MPEG-TS byte stream and records their timestamps.
+func AnalyzeTS(data []byte) TSInfo {
+	var info TSInfo
+	for _, p := range packets(data) {
+		if !p.payloadStart {
+			continue
+		}
+		isVideo, pts, hasPTS := videoPES(p.payload)
+		if !isVideo {
+			continue
+		}
+		info.Frames++
+		if hasPTS {
+			if !info.HasPTS {
+				info.FirstPTS = pts
+				info.HasPTS = true
+			}
+			info.LastPTS = pts
+		}
+	}
+	return info
+}
+
+// SplitTS splits an MPEG-TS byte stream into segments of at least the target duration.
+// Segments are cut at video keyframes, and the PAT/PMT packets found in the stream are
+// repeated at the start of every segment.
+func SplitTS(data []byte, target time.Duration) []Segment {
+	all := packets(data)
+
+	// Collect the program association and program map packets.
+	pmtPIDs := make(map[uint16]bool)
+	var tables [][]byte
+	for _, p := range all {
+		if p.pid == 0 && p.payloadStart && len(tables) == 0 {
+			tables = append(tables, p.raw)
+			for pid := range patProgramMapPIDs(p.payload) {
+				pmtPIDs[pid] = true
+			}
+		} else if pmtPIDs[p.pid] && p.payloadStart && len(tables) == 1 {
+			tables = append(tables, p.raw)
+		}
+	}
+
+	var segments []Segment
+	var current []byte
+	var frames int
+	var startPTS, lastPTS int64
+	var havePTS bool
+	flush := func() {
+		if frames == 0 {
+			return
+		}
+		seg := Segment{Frames: frames}
+		for _, t := range tables {
+			seg.Data = append(seg.Data, t...)
+		}
+		seg.Data = append(seg.Data, current...)
+		if havePTS && lastPTS > startPTS {
+			seg.Duration = time.Duration(lastPTS-startPTS) * time.Second / ptsClockRate
+		}
+		segments = append(segments, seg)
+		current, frames, havePTS = nil, 0, false
+	}
+
+	for _, p := range all {
+		if p.pid == 0 || pmtPIDs[p.pid] {
+			continue
+		}
+		if p.payloadStart {
+			if isVideo, pts, hasPTS := videoPES(p.payload); isVideo {
+				if p.randomAccess && havePTS && hasPTS && time.Duration(pts-startPTS)*time.Second/ptsClockRate >= target {
+					lastPTS = pts
+					flush()
+				}
+				frames++
+				if hasPTS {
+					if !havePTS {
+						startPTS = pts
+						havePTS = true
+					}
+					lastPTS = pts
+				}
+			}
+		}
+		current = append(current, p.raw...)
+	}
+	flush()
+	return segments
+}
+
+// patProgramMapPIDs returns the program map PIDs listed in a program association table payload.
+func patProgramMapPIDs(payload []byte) map[uint16]bool {
+	pids := make(map[uint16]bool)
+	if len(payload) < 1 || 1+int(payload[0]) >= len(payload) {
+		return pids
+	}
+	section := payload[1+int(payload[0]):]
+	if len(section) < 8 || section[0] != 0 {
+		return pids
+	}
+	length := int(section[1]&0x0f)<<8 | int(section[2])
+	end := 3 + length - 4 // exclude CRC32
+	if end > len(section) {
+		end = len(section)
+	}
+	for i := 8; i+4 <= end; i += 4 {
+		program := uint16(section[i])<<8 | uint16(section[i+1])
+		pid := uint16(section[i+2]&0x1f)<<8 | uint16(section[i+3])
+		if program != 0 {
+			pids[pid] = true
+		}
+	}
+	return pids
+}
@@ -0,0 +1,330 @@
+package stream
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrNotReady is returned by Subscribe when the requested output segment is not available yet.
+var ErrNotReady = errors.New("segment not ready")
+
+// Session is a live video session opened through the gateway. Input segments are published
+// with sequence numbers to PublishURL and output segments are read back from SubscribeURL.
+type Session struct {
+	client       *http.Client
+	token        string
+	PublishURL   string `json:"publish_url"`
+	SubscribeURL string `json:"subscribe_url"`
+	ControlURL   string `json:"control_url"`
+
+	StatusCode int         `json:"-"` // Status code of the start response.
+	Header     http.Header `json:"-"` // Headers of the start response, e.g. naming the serving orchestrator.
+}
+
+// Options controls the pacing and timeouts of a streaming test.
+type Options struct {
+	SegmentDuration   time.Duration // Target length of each published segment, used when segments carry no timestamps.
+	FirstFrameTimeout time.Duration // How long to wait for the first output frame.
+	IdleTimeout       time.Duration // How long to wait for more output once publishing has finished.
+}
+
+// Result holds the measurements of a streaming test.
+type Result struct {
+	TimeToFirstFrame time.Duration // Time from the first published segment to the first output frame.
+	FramesSent       int
+	FramesReceived   int
+	SegmentsSent     int
+	SegmentsReceived int
+	OutputFPS        float64
+}
+
+// DroppedFrames returns the number of input frames missing from the output.
+func (r Result) DroppedFrames() int {
+	if r.FramesReceived >= r.FramesSent {
+		return 0
+	}
+	return r.FramesSent - r.FramesReceived
+}
+
+// Start opens a live video session by posting the request body to the gateway start URL.
+// The response may include publish/subscribe URLs, which are resolved relative to the start URL;
+// otherwise the trickle defaults "<stream>" and "<stream>-out" next to the start URL are used.
+func Start(ctx context.Context, client *http.Client, startURL, streamURL, token string, body []byte) (*Session, error) {
+	req, err := http.NewRequestWithContext(ctx, "POST", startURL, bytes.NewBuffer(body))
+	if err != nil {
+		return nil, fmt.Errorf("[stream::Start] failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("[stream::Start] failed to start session: %w", err)
+	}
+	defer res.Body.Close()
+
+	resBody, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, fmt.Errorf("[stream::Start] failed to read response body: %w", err)
+	}
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return nil, &StatusError{StatusCode: res.StatusCode, Body: string(resBody)}
+	}
+
+	session := &Session{client: client, token: token, StatusCode: res.StatusCode, Header: res.Header}
+	if len(bytes.TrimSpace(resBody)) > 0 {
+		// Older gateways reply with plain text; only JSON replies carry session URLs.
+		_ = json.Unmarshal(resBody, session)
+	}
+	if session.PublishURL == "" {
+		session.PublishURL = streamURL
+	}
+	if session.SubscribeURL == "" {
+		session.SubscribeURL = streamURL + "-out"
+	}
+	for _, u := range []*string{&session.PublishURL, &session.SubscribeURL, &session.ControlURL} {
+		if *u != "" {
+			if *u, err = resolveURL(startURL, *u); err != nil {
+				return nil, fmt.Errorf("[stream::Start] invalid session URL: %w", err)
+			}
+		}
+	}
+	return session, nil
+}
+
+// StatusError is returned when the gateway answers a session request with a non-2xx status code.
+type StatusError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("unexpected status code %d: %s", e.StatusCode, e.Body)
+}
+
+// Publish posts an input segment with the given sequence number.
+func (s *Session) Publish(ctx context.Context, seq int, data []byte) error {
+	req, err := http.NewRequestWithContext(ctx, "POST", fmt.Sprintf("%s/%d", s.PublishURL, seq), bytes.NewBuffer(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "video/mp2t")
+	req.Header.Set("Authorization", "Bearer "+s.token)
+
+	res, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("[Session::Publish] segment %d: %w", seq, err)
+	}
+	defer res.Body.Close()
+	io.Copy(io.Discard, res.Body)
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return fmt.Errorf("[Session::Publish] segment %d: unexpected status code %d", seq, res.StatusCode)
+	}
+	return nil
+}
+
+// Subscribe reads the output segment with the given sequence number.
+// It returns ErrNotReady if the segment hasn't been produced yet.
+func (s *Session) Subscribe(ctx context.Context, seq int) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", fmt.Sprintf("%s/%d", s.SubscribeURL, seq), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+s.token)
+
+	res, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("[Session::Subscribe] segment %d: %w", seq, err)
+	}
+	defer res.Body.Close()
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, fmt.Errorf("[Session::Subscribe] segment %d: %w", seq, err)
+	}
+
+	switch {
+	case res.StatusCode == http.StatusNotFound || res.StatusCode == 470:
+		return nil, ErrNotReady
+	case res.StatusCode < 200 || res.StatusCode >= 300:
+		return nil, fmt.Errorf("[Session::Subscribe] segment %d: unexpected status code %d", seq, res.StatusCode)
+	case len(body) == 0:
+		return nil, ErrNotReady
+	}
+	return body, nil
+}
+
+// Stop tears the session down by closing the publish stream.
+func (s *Session) Stop(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, "DELETE", s.PublishURL, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+s.token)
+
+	res, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("[Session::Stop] %w", err)
+	}
+	defer res.Body.Close()
+	io.Copy(io.Discard, res.Body)
+	if res.StatusCode >= 300 && res.StatusCode != http.StatusNotFound {
+		return fmt.Errorf("[Session::Stop] unexpected status code %d", res.StatusCode)
+	}
+	return nil
+}
+
+// Run publishes the segments in real time while reading the output back, and measures
+// time-to-first-frame, output frame rate and dropped frames. It returns an error if
+// publishing fails, no output frame arrives within the first frame timeout or the context
+// ends the run, along with the measurements made so far.
+func (s *Session) Run(parent context.Context, segments []Segment, opts Options) (Result, error) {
+	var result Result
+	for _, seg := range segments {
+		result.FramesSent += seg.Frames
+	}
+
+	ctx, cancel := context.WithCancel(parent)
+	defer cancel()
+
+	var (
+		wg         sync.WaitGroup
+		lock       sync.Mutex
+		started    time.Time
+		publishErr error
+		doneAt     time.Time
+	)
+
+	// Publish the input segments at their real-time pace.
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for seq, seg := range segments {
+			if seq == 0 {
+				lock.Lock()
+				started = time.Now()
+				lock.Unlock()
+			}
+			sentAt := time.Now()
+			if err := s.Publish(ctx, seq, seg.Data); err != nil {
+				lock.Lock()
+				publishErr = err
+				lock.Unlock()
+				cancel()
+				return
+			}
+			lock.Lock()
+			result.SegmentsSent++
+			lock.Unlock()
+
+			pace := seg.Duration
+			if pace == 0 {
+				pace = opts.SegmentDuration
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(pace - time.Since(sentAt)):
+			}
+		}
+		lock.Lock()
+		doneAt = time.Now()
+		lock.Unlock()
+	}()
+
+	// Read the output segments until publishing is done and no more output arrives.
+	var output TSInfo
+	var firstAt, lastAt time.Time
+	lastProgress := time.Now()
+	for seq := 0; ctx.Err() == nil; {
+		data, err := s.Subscribe(ctx, seq)
+		now := time.Now()
+		if err == nil {
+			info := AnalyzeTS(data)
+			lock.Lock()
+			result.SegmentsReceived++
+			if info.Frames > 0 && result.FramesReceived == 0 && !started.IsZero() {
+				result.TimeToFirstFrame = now.Sub(started)
+			}
+			lock.Unlock()
+			if info.Frames > 0 {
+				if firstAt.IsZero() {
+					firstAt = now
+					output.FirstPTS, output.HasPTS = info.FirstPTS, info.HasPTS
+				}
+				lastAt = now
+				output.LastPTS = info.LastPTS
+				result.FramesReceived += info.Frames
+			}
+			lastProgress = now
+			seq++
+			continue
+		}
+		if !errors.Is(err, ErrNotReady) {
+			if ctx.Err() != nil {
+				break
+			}
+			cancel()
+			wg.Wait()
+			return result, err
+		}
+
+		lock.Lock()
+		finished := !doneAt.IsZero()
+		lock.Unlock()
+		if result.FramesReceived == 0 && now.Sub(lastProgress) > opts.FirstFrameTimeout {
+			cancel()
+			wg.Wait()
+			return result, fmt.Errorf("[Session::Run] no output frame received within %v", opts.FirstFrameTimeout)
+		}
+		if finished && result.FramesReceived > 0 && now.Sub(lastProgress) > opts.IdleTimeout {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+	cancel()
+	wg.Wait()
+
+	if publishErr != nil {
+		return result, publishErr
+	}
+
+	// Prefer the output timestamps for the frame rate, falling back to wall clock time.
+	duration := output.Duration()
+	if duration <= 0 {
+		duration = lastAt.Sub(firstAt)
+	}
+	if duration > 0 && result.FramesReceived > 1 {
+		result.OutputFPS = float64(result.FramesReceived-1) / duration.Seconds()
+	}
+	if err := parent.Err(); err != nil {
+		return result, fmt.Errorf("[Session::Run] stream ended before it completed: %w", err)
+	}
+	return result, nil
+}
+
+// resolveURL resolves a possibly relative session URL against the start URL.
+func resolveURL(base, ref string) (string, error) {
+	baseURL, err := url.Parse(base)
+	if err != nil {
+		return "", err
+	}
+	refURL, err := url.Parse(ref)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(baseURL.ResolveReference(refURL).String(), "/"), nil
+}
@@ -0,0 +1,36 @@
+package stream
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"time"
+)
+
+// Synthesize generates an MPEG-TS test pattern video with ffmpeg, which must be on the PATH.
+// The video has a keyframe every second so it can be split into one second segments.
+func Synthesize(ctx context.Context, duration time.Duration, width, height, fps int) ([]byte, error) {
+	ffmpeg, err := exec.LookPath("ffmpeg")
+	if err != nil {
+		return nil, fmt.Errorf("[stream::Synthesize] ffmpeg is required for synthetic video: %w", err)
+	}
+
+	source := fmt.Sprintf("testsrc2=size=%dx%d:rate=%d", width, height, fps)
+	cmd := exec.CommandContext(ctx, ffmpeg,
+		"-hide_banner", "-loglevel", "error",
+		"-f", "lavfi", "-i", source,
+		"-t", strconv.FormatFloat(duration.Seconds(), 'f', 3, 64),
+		"-c:v", "libx264", "-preset", "ultrafast", "-tune", "zerolatency",
+		"-pix_fmt", "yuv420p", "-g", strconv.Itoa(fps), "-bf", "0",
+		"-f", "mpegts", "pipe:1",
+	)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("[stream::Synthesize] ffmpeg failed: %w: %s", err, stderr.String())
+	}
+	return stdout.Bytes(), nil
+}
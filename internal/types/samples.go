@@ -0,0 +1,108 @@
+package types
+
+import (
+	"math"
+	"sort"
+)
+
+// AddSample appends a sample to the stats and merges its error, if any, into Errors.
+// Errors with the same code and message are counted rather than repeated.
+func (s *Stats) AddSample(sample Sample) {
+	s.Samples = append(s.Samples, sample)
+	if sample.Error != nil {
+		s.AddError(*sample.Error)
+	}
+}
+
+// AddError merges an error into Errors, incrementing the count of a matching entry if one exists.
+func (s *Stats) AddError(e Error) {
+	for i := range s.Errors {
+		if s.Errors[i].ErrorCode == e.ErrorCode && s.Errors[i].Message == e.Message {
+			s.Errors[i].Count += e.Count
+			return
+		}
+	}
+	s.Errors = append(s.Errors, e)
+}
+
+// Summarize computes SuccessRate, RoundTripTime, Latency, LLM and Media from the collected samples.
+// It is a no-op when no samples were recorded.
+func (s *Stats) Summarize() {
+	if len(s.Samples) == 0 {
+		return
+	}
+
+	rtts := make([]float64, 0, len(s.Samples))
+	succeeded := 0
+	total := 0.0
+	for _, sample := range s.Samples {
+		if sample.Success {
+			succeeded++
+		}
+		rtts = append(rtts, sample.RoundTripTime)
+		total += sample.RoundTripTime
+	}
+	sort.Float64s(rtts)
+
+	s.SuccessRate = float64(succeeded) / float64(len(s.Samples))
+	s.Latency = &LatencyStats{
+		Min:  rtts[0],
+		Mean: total / float64(len(rtts)),
+		P50:  percentile(rtts, 50),
+		P95:  percentile(rtts, 95),
+		Max:  rtts[len(rtts)-1],
+	}
+	s.RoundTripTime = s.Latency.Mean
+	s.LLM = summarizeLLM(s.Samples)
+	for _, sample := range s.Samples {
+		if len(sample.Media) > 0 {
+			s.Media = sample.Media
+		}
+	}
+}
+
+// summarizeLLM averages the time-to-first-token and tokens per second of the samples that streamed tokens
+// and sums their tokens. The stream is only considered complete if every sample's stream completed.
+// It returns nil when no sample was a streamed LLM response.
+func summarizeLLM(samples []Sample) *LLMStats {
+	var summary *LLMStats
+	streamed := 0
+	for _, sample := range samples {
+		if sample.LLM == nil {
+			continue
+		}
+		if summary == nil {
+			summary = &LLMStats{StreamCompleted: true}
+		}
+		summary.StreamCompleted = summary.StreamCompleted && sample.LLM.StreamCompleted
+		if sample.LLM.TotalTokens <= 0 {
+			continue
+		}
+		streamed++
+		summary.TimeToFirstToken += sample.LLM.TimeToFirstToken
+		summary.TokensPerSecond += sample.LLM.TokensPerSecond
+		summary.TotalTokens += sample.LLM.TotalTokens
+	}
+	if streamed > 0 {
+		summary.TimeToFirstToken /= float64(streamed)
+		summary.TokensPerSecond /= float64(streamed)
+	}
+	return summary
+}
+
+// Summary returns a copy of the stats without the raw samples.
+// It is used for sinks that expect a single aggregated record per job.
+func (s *Stats) Summary() *Stats {
+	summary := *s
+	summary.Samples = nil
+	return &summary
+}
+
+// percentile returns the nearest-rank percentile p (0-100) of an ascending sorted slice.
+func percentile(sorted []float64, p float64) float64 {
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
@@ -3,18 +3,87 @@ package types
 // Stats represents the raw statistics per test stream, capturing details such as
 // the region, pipeline used, model details, success rate, and round-trip time.
 // It also stores errors encountered during the test and a timestamp.
+// When a job is made of several samples, SuccessRate is the fraction of samples that succeeded,
+// RoundTripTime is the mean sample latency, and Latency/Samples hold the distribution and raw data.
+// Live video streaming jobs also carry their stream measurements in Stream, and
+// streamed LLM jobs their token measurements in LLM. Media holds the inspected outputs of the last sample.
+// ServedBy is the orchestrator verified to have served the job, when verification is configured.
 type Stats struct {
-	Region          string  `json:"region"`
-	Pipeline        string  `json:"pipeline"`
-	Model           string  `json:"model"`
-	ModelIsWarm     bool    `json:"model_is_warm"`
-	InputParameters string  `json:"input_parameters"`
-	ResponsePayload string  `json:"response_payload"`
-	Orchestrator    string  `json:"orchestrator"`
-	SuccessRate     int     `json:"success_rate"`
-	RoundTripTime   float64 `json:"round_trip_time"`
-	Errors          []Error `json:"errors"`
-	Timestamp       int64   `json:"timestamp"`
+	Region          string        `json:"region"`
+	Pipeline        string        `json:"pipeline"`
+	Scenario        string        `json:"scenario,omitempty"`
+	Model           string        `json:"model"`
+	ModelIsWarm     bool          `json:"model_is_warm"`
+	InputParameters string        `json:"input_parameters"`
+	ResponsePayload string        `json:"response_payload"`
+	Orchestrator    string        `json:"orchestrator"`
+	ServedBy        string        `json:"served_by,omitempty"`
+	SuccessRate     float64       `json:"success_rate"`
+	RoundTripTime   float64       `json:"round_trip_time"`
+	Latency         *LatencyStats `json:"latency,omitempty"`
+	Samples         []Sample      `json:"samples,omitempty"`
+	Stream          *StreamStats  `json:"stream,omitempty"`
+	LLM             *LLMStats     `json:"llm,omitempty"`
+	Media           []MediaInfo   `json:"media,omitempty"`
+	Errors          []Error       `json:"errors"`
+	Timestamp       int64         `json:"timestamp"`
+}
+
+// Sample represents a single request sent as part of a test job.
+// It records the round-trip time, whether the request succeeded and the error, if any.
+type Sample struct {
+	RoundTripTime float64     `json:"round_trip_time"`
+	Success       bool        `json:"success"`
+	StatusCode    int         `json:"status_code,omitempty"`
+	ServedBy      string      `json:"served_by,omitempty"`
+	Error         *Error      `json:"error,omitempty"`
+	LLM           *LLMStats   `json:"llm,omitempty"`
+	Media         []MediaInfo `json:"media,omitempty"`
+	Timestamp     int64       `json:"timestamp"`
+}
+
+// MediaInfo describes a generated image, video or audio output inspected by the job tester.
+// Dimensions apply to images and video, frames and fps to video, and duration (in seconds) to video and audio.
+type MediaInfo struct {
+	URL      string  `json:"url"`
+	Format   string  `json:"format"`
+	Bytes    int     `json:"bytes"`
+	Width    int     `json:"width,omitempty"`
+	Height   int     `json:"height,omitempty"`
+	Frames   int     `json:"frames,omitempty"`
+	FPS      float64 `json:"fps,omitempty"`
+	Duration float64 `json:"duration,omitempty"`
+	Blank    bool    `json:"blank,omitempty"`
+}
+
+// LLMStats holds the measurements of a streamed LLM response.
+// TimeToFirstToken is in seconds, and StreamCompleted is set when the stream ended with a completion event.
+type LLMStats struct {
+	TimeToFirstToken float64 `json:"time_to_first_token"`
+	TokensPerSecond  float64 `json:"tokens_per_second"`
+	TotalTokens      int     `json:"total_tokens"`
+	StreamCompleted  bool    `json:"stream_completed"`
+}
+
+// StreamStats holds the measurements of a live video streaming job.
+// Times are in seconds; dropped frames are input frames missing from the output.
+type StreamStats struct {
+	TimeToFirstFrame float64 `json:"time_to_first_frame"`
+	OutputFPS        float64 `json:"output_fps"`
+	FramesSent       int     `json:"frames_sent"`
+	FramesReceived   int     `json:"frames_received"`
+	DroppedFrames    int     `json:"dropped_frames"`
+	SegmentsSent     int     `json:"segments_sent"`
+	SegmentsReceived int     `json:"segments_received"`
+}
+
+// LatencyStats summarizes the round-trip times (in seconds) of all samples in a job.
+type LatencyStats struct {
+	Min  float64 `json:"min"`
+	Mean float64 `json:"mean"`
+	P50  float64 `json:"p50"`
+	P95  float64 `json:"p95"`
+	Max  float64 `json:"max"`
 }
 
 // Error represents the details of an error encountered during a test job.
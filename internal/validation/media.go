@@ -0,0 +1,389 @@
+package validation
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/jpeg" // Register the JPEG decoder.
+	_ "image/png"  // Register the PNG decoder.
+	"io/ioutil"
+	"livepeer-job-tester/internal/config"
+	"livepeer-job-tester/internal/types"
+	"math"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// maxMediaBytes limits the size of a downloaded media output.
+const maxMediaBytes = 256 << 20
+
+// Inspector downloads or decodes the media outputs of a job response and checks that they are
+// valid PNG, JPEG, MP4 or WAV files matching the requested parameters.
+type Inspector struct {
+	client  *http.Client
+	baseURL string
+}
+
+// NewInspector creates an Inspector. Relative output URLs returned by the gateway are resolved against baseURL.
+func NewInspector(client *http.Client, baseURL string) *Inspector {
+	return &Inspector{client: client, baseURL: baseURL}
+}
+
+// Inspect checks every "url" in the response body, which may be an http(s) URL, a path on the
+// gateway or a data URI. Image dimensions are compared to the "width"/"height" parameters, video
+// frame rate and frame count to "fps"/"num_frames", and blank or single colour images fail unless
+// the validator allows them. It returns the metadata of every output, even when a check fails.
+func (in *Inspector) Inspect(v *config.Validator, body []byte, params map[string]interface{}) ([]types.MediaInfo, error) {
+	var doc interface{}
+	if err := json.Unmarshal(body, &doc); err != nil {
+		return nil, fmt.Errorf("[Inspector::Inspect] response is not JSON: %w", err)
+	}
+	urls := collectURLs(doc, nil)
+	if len(urls) == 0 {
+		return nil, errors.New("[Inspector::Inspect] response contains no media url")
+	}
+
+	var media []types.MediaInfo
+	var errs []error
+	for _, u := range urls {
+		data, err := in.fetch(u)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		info, err := inspectMedia(data)
+		info.URL = displayURL(u)
+		media = append(media, info)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", info.URL, err))
+			continue
+		}
+		if err := checkMedia(info, v, params); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", info.URL, err))
+		}
+	}
+	return media, errors.Join(errs...)
+}
+
+// collectURLs returns the string values of every "url" field in the document, visiting object keys in sorted order.
+func collectURLs(node interface{}, urls []string) []string {
+	switch n := node.(type) {
+	case map[string]interface{}:
+		if u, ok := n["url"].(string); ok && u != "" {
+			urls = append(urls, u)
+		}
+		keys := make([]string, 0, len(n))
+		for key := range n {
+			if key != "url" {
+				keys = append(keys, key)
+			}
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			urls = collectURLs(n[key], urls)
+		}
+	case []interface{}:
+		for _, item := range n {
+			urls = collectURLs(item, urls)
+		}
+	}
+	return urls
+}
+
+// fetch decodes a data URI or downloads the media at the URL.
+func (in *Inspector) fetch(ref string) ([]byte, error) {
+	if strings.HasPrefix(ref, "data:") {
+		comma := strings.IndexByte(ref, ',')
+		if comma < 0 {
+			return nil, errors.New("[Inspector::fetch] invalid data URI")
+		}
+		if strings.HasSuffix(ref[:comma], ";base64") {
+			return base64.StdEncoding.DecodeString(ref[comma+1:])
+		}
+		decoded, err := url.PathUnescape(ref[comma+1:])
+		return []byte(decoded), err
+	}
+
+	base, err := url.Parse(in.baseURL + "/")
+	if err != nil {
+		return nil, fmt.Errorf("[Inspector::fetch] invalid base URL: %w", err)
+	}
+	target, err := url.Parse(ref)
+	if err != nil {
+		return nil, fmt.Errorf("[Inspector::fetch] invalid media URL %q: %w", ref, err)
+	}
+	res, err := in.client.Get(base.ResolveReference(target).String())
+	if err != nil {
+		return nil, fmt.Errorf("[Inspector::fetch] failed to download %s: %w", ref, err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return nil, fmt.Errorf("[Inspector::fetch] failed to download %s: status code %d", ref, res.StatusCode)
+	}
+	data, err := ioutil.ReadAll(http.MaxBytesReader(nil, res.Body, maxMediaBytes))
+	if err != nil {
+		return nil, fmt.Errorf("[Inspector::fetch] failed to read %s: %w", ref, err)
+	}
+	return data, nil
+}
+
+// displayURL shortens data URIs so the media metadata doesn't repeat the whole payload.
+func displayURL(ref string) string {
+	if strings.HasPrefix(ref, "data:") {
+		if comma := strings.IndexByte(ref, ','); comma >= 0 {
+			return ref[:comma] + ",..."
+		}
+	}
+	return ref
+}
+
+// inspectMedia detects the format of the data and extracts its metadata.
+func inspectMedia(data []byte) (types.MediaInfo, error) {
+	info := types.MediaInfo{Bytes: len(data)}
+	switch {
+	case bytes.HasPrefix(data, []byte("\x89PNG\r\n\x1a\n")):
+		info.Format = "png"
+		return inspectImage(data, info)
+	case bytes.HasPrefix(data, []byte{0xff, 0xd8, 0xff}):
+		info.Format = "jpeg"
+		return inspectImage(data, info)
+	case len(data) >= 12 && string(data[4:8]) == "ftyp":
+		info.Format = "mp4"
+		return inspectMP4(data, info)
+	case len(data) >= 12 && string(data[0:4]) == "RIFF" && string(data[8:12]) == "WAVE":
+		info.Format = "wav"
+		return inspectWAV(data, info)
+	default:
+		return info, errors.New("unsupported or invalid media format")
+	}
+}
+
+// inspectImage decodes a PNG or JPEG image, recording its dimensions and whether it is a single colour.
+func inspectImage(data []byte, info types.MediaInfo) (types.MediaInfo, error) {
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return info, fmt.Errorf("invalid %s image: %w", info.Format, err)
+	}
+	bounds := img.Bounds()
+	info.Width, info.Height = bounds.Dx(), bounds.Dy()
+	info.Blank = isSingleColour(img)
+	return info, nil
+}
+
+// isSingleColour samples a grid of pixels and reports whether they are all (nearly) the same colour.
+func isSingleColour(img image.Image) bool {
+	const grid = 64
+	const tolerance = 8 << 8 // on the 16-bit colour scale returned by RGBA()
+	bounds := img.Bounds()
+	if bounds.Empty() {
+		return true
+	}
+	r0, g0, b0, _ := img.At(bounds.Min.X, bounds.Min.Y).RGBA()
+	for i := 0; i < grid; i++ {
+		for j := 0; j < grid; j++ {
+			x := bounds.Min.X + i*bounds.Dx()/grid
+			y := bounds.Min.Y + j*bounds.Dy()/grid
+			r, g, b, _ := img.At(x, y).RGBA()
+			if absDiff(r, r0) > tolerance || absDiff(g, g0) > tolerance || absDiff(b, b0) > tolerance {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func absDiff(a, b uint32) uint32 {
+	if a > b {
+		return a - b
+	}
+	return b - a
+}
+
+// inspectMP4 walks the ISO BMFF boxes of an MP4 file and records the dimensions, frame count,
+// frame rate and duration of its first video track.
+func inspectMP4(data []byte, info types.MediaInfo) (types.MediaInfo, error) {
+	moov := findBox(data, "moov")
+	if moov == nil {
+		return info, errors.New("invalid mp4: no moov box")
+	}
+	if mvhd := findBox(moov, "mvhd"); mvhd != nil {
+		if timescale, duration, ok := headerDuration(mvhd); ok && timescale > 0 {
+			info.Duration = float64(duration) / float64(timescale)
+		}
+	}
+
+	for _, trak := range findBoxes(moov, "trak") {
+		mdia := findBox(trak, "mdia")
+		if mdia == nil {
+			continue
+		}
+		if hdlr := findBox(mdia, "hdlr"); len(hdlr) < 12 || string(hdlr[8:12]) != "vide" {
+			continue
+		}
+		if tkhd := findBox(trak, "tkhd"); len(tkhd) >= 84 {
+			// Width and height are 16.16 fixed point values at the end of the track header.
+			info.Width = int(binary.BigEndian.Uint32(tkhd[len(tkhd)-8:]) >> 16)
+			info.Height = int(binary.BigEndian.Uint32(tkhd[len(tkhd)-4:]) >> 16)
+		}
+		if stsz := findBox(findBox(findBox(mdia, "minf"), "stbl"), "stsz"); len(stsz) >= 12 {
+			info.Frames = int(binary.BigEndian.Uint32(stsz[8:12]))
+		}
+		if mdhd := findBox(mdia, "mdhd"); mdhd != nil {
+			if timescale, duration, ok := headerDuration(mdhd); ok && timescale > 0 && duration > 0 {
+				trackDuration := float64(duration) / float64(timescale)
+				info.FPS = math.Round(float64(info.Frames)/trackDuration*100) / 100
+				if info.Duration == 0 {
+					info.Duration = trackDuration
+				}
+			}
+		}
+		return info, nil
+	}
+	return info, errors.New("invalid mp4: no video track")
+}
+
+// headerDuration reads the timescale and duration of a version 0 or 1 mvhd/mdhd box payload.
+func headerDuration(box []byte) (timescale uint32, duration uint64, ok bool) {
+	if len(box) < 4 {
+		return 0, 0, false
+	}
+	if box[0] == 1 {
+		if len(box) < 32 {
+			return 0, 0, false
+		}
+		return binary.BigEndian.Uint32(box[20:24]), binary.BigEndian.Uint64(box[24:32]), true
+	}
+	if len(box) < 20 {
+		return 0, 0, false
+	}
+	return binary.BigEndian.Uint32(box[12:16]), uint64(binary.BigEndian.Uint32(box[16:20])), true
+}
+
+// findBox returns the payload of the first child box of the given type, or nil.
+func findBox(data []byte, boxType string) []byte {
+	boxes := findBoxes(data, boxType)
+	if len(boxes) == 0 {
+		return nil
+	}
+	return boxes[0]
+}
+
+// findBoxes returns the payloads of every child box of the given type.
+func findBoxes(data []byte, boxType string) [][]byte {
+	var boxes [][]byte
+	for offset := 0; offset+8 <= len(data); {
+		size := uint64(binary.BigEndian.Uint32(data[offset : offset+4]))
+		name := string(data[offset+4 : offset+8])
+		header := uint64(8)
+		switch size {
+		case 0:
+			size = uint64(len(data) - offset)
+		case 1:
+			if offset+16 > len(data) {
+				return boxes
+			}
+			size = binary.BigEndian.Uint64(data[offset+8 : offset+16])
+			header = 16
+		}
+		if size < header || uint64(offset)+size > uint64(len(data)) {
+			return boxes
+		}
+		if name == boxType {
+			boxes = append(boxes, data[uint64(offset)+header:uint64(offset)+size])
+		}
+		offset += int(size)
+	}
+	return boxes
+}
+
+// inspectWAV reads the fmt and data chunks of a WAV file and records its duration.
+func inspectWAV(data []byte, info types.MediaInfo) (types.MediaInfo, error) {
+	var byteRate uint32
+	for offset := 12; offset+8 <= len(data); {
+		id := string(data[offset : offset+4])
+		size := int(binary.LittleEndian.Uint32(data[offset+4 : offset+8]))
+		body := data[offset+8:]
+		if size < len(body) {
+			body = body[:size]
+		}
+		switch id {
+		case "fmt ":
+			if len(body) < 16 {
+				return info, errors.New("invalid wav: short fmt chunk")
+			}
+			byteRate = binary.LittleEndian.Uint32(body[8:12])
+		case "data":
+			if byteRate == 0 {
+				return info, errors.New("invalid wav: data chunk before fmt chunk")
+			}
+			info.Duration = float64(len(body)) / float64(byteRate)
+			return info, nil
+		}
+		offset += 8 + size + size%2
+	}
+	return info, errors.New("invalid wav: no data chunk")
+}
+
+// checkMedia compares the media metadata with the validator settings and the requested parameters.
+func checkMedia(info types.MediaInfo, v *config.Validator, params map[string]interface{}) error {
+	switch info.Format {
+	case "png", "jpeg":
+		if info.Blank && (v == nil || !v.AllowBlank) {
+			return errors.New("image is blank or a single colour")
+		}
+		return checkDimensions(info, params)
+	case "mp4":
+		if info.Frames == 0 {
+			return errors.New("video has no frames")
+		}
+		if err := checkDimensions(info, params); err != nil {
+			return err
+		}
+		if fps, ok := numberParam(params, "fps"); ok && fps > 0 && math.Abs(info.FPS-fps) > 0.5 {
+			return fmt.Errorf("video frame rate %v does not match requested fps %v", info.FPS, fps)
+		}
+		if frames, ok := numberParam(params, "num_frames"); ok && frames > 0 && info.Frames != int(frames) {
+			return fmt.Errorf("video has %d frames, requested %v", info.Frames, frames)
+		}
+	case "wav":
+		if info.Duration <= 0 {
+			return errors.New("audio is empty")
+		}
+	}
+	return nil
+}
+
+// checkDimensions compares the media size to the requested "width" and "height" parameters.
+func checkDimensions(info types.MediaInfo, params map[string]interface{}) error {
+	if width, ok := numberParam(params, "width"); ok && width > 0 && info.Width != int(width) {
+		return fmt.Errorf("%s width %d does not match requested width %v", info.Format, info.Width, width)
+	}
+	if height, ok := numberParam(params, "height"); ok && height > 0 && info.Height != int(height) {
+		return fmt.Errorf("%s height %d does not match requested height %v", info.Format, info.Height, height)
+	}
+	return nil
+}
+
+// numberParam returns a numeric job parameter, whether it was configured as a number or rendered from a template.
+func numberParam(params map[string]interface{}, key string) (float64, bool) {
+	switch v := params[key].(type) {
+	case float64:
+		return v, true
+	case int:
+		return float64(v), true
+	case json.Number:
+		f, err := v.Float64()
+		return f, err == nil
+	case string:
+		f, err := strconv.ParseFloat(v, 64)
+		return f, err == nil
+	}
+	return 0, false
+}
@@ -0,0 +1,72 @@
+package validation
+
+import (
+	"encoding/json"
+	"fmt"
+	"livepeer-job-tester/internal/config"
+	"strconv"
+	"strings"
+)
+
+// Validate checks the body of a successful job response against the validator configuration.
+// A nil validator, or one of type "status", accepts any body. The media of "media" validators
+// is checked separately by an Inspector.
+func Validate(v *config.Validator, body []byte) error {
+	if v == nil {
+		return nil
+	}
+
+	switch v.Type {
+	case "", config.ValidatorStatus:
+		return nil
+	case config.ValidatorJSON, config.ValidatorMedia:
+		return validateJSON(v, body)
+	default:
+		return fmt.Errorf("[Validate] unknown validator type: %s", v.Type)
+	}
+}
+
+// validateJSON checks the body is a JSON object containing every required field.
+// Nested fields are referenced with dots and array elements by index, e.g. "images.0.url".
+func validateJSON(v *config.Validator, body []byte) error {
+	var doc map[string]interface{}
+	if err := json.Unmarshal(body, &doc); err != nil {
+		return fmt.Errorf("[validateJSON] response is not a JSON object: %w", err)
+	}
+
+	for _, field := range v.RequiredFields {
+		if !hasField(doc, field) {
+			return fmt.Errorf("[validateJSON] response is missing required field: %s", field)
+		}
+	}
+	return nil
+}
+
+// hasField reports whether the dotted field path exists and is not null in the document.
+func hasField(doc interface{}, path string) bool {
+	current := doc
+	for _, key := range strings.Split(path, ".") {
+		switch node := current.(type) {
+		case map[string]interface{}:
+			value, ok := node[key]
+			if !ok {
+				return false
+			}
+			current = value
+		case []interface{}:
+			index, err := strconv.Atoi(key)
+			if err != nil || index < 0 || index >= len(node) {
+				return false
+			}
+			current = node[index]
+		default:
+			return false
+		}
+	}
+	return current != nil
+}
+
+// InspectsMedia reports whether the validator requires the generated media to be inspected.
+func InspectsMedia(v *config.Validator) bool {
+	return v != nil && v.Type == config.ValidatorMedia
+}